@@ -0,0 +1,105 @@
+package httptool
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// AdaptiveLimiter 按 AIMD(加性增、乘性减) 自动调整在途请求数上限的限流器
+// 请求成功时上限缓慢增加(每个上限窗口内全部成功约加 1), 超时或 5xx 时上限减半, 用于在不手动调参的情况下找到后端能承受的并发数
+// 同一个 AdaptiveLimiter 可以在多个请求间共享, 通常每个后端一个
+type AdaptiveLimiter struct {
+	mu                 sync.Mutex
+	limit              float64
+	minLimit, maxLimit float64
+	inFlight           int
+	changed            chan struct{} // 有名额释放或上限变化时关闭, 唤醒等待的请求
+}
+
+// NewAdaptiveLimiter 创建自适应限流器, 上限从 initial 开始, 在 [minLimit, maxLimit] 之间调整, minLimit 至少为 1
+func NewAdaptiveLimiter(initial, minLimit, maxLimit int) *AdaptiveLimiter {
+	minLimit = max(minLimit, 1)
+	maxLimit = max(maxLimit, minLimit)
+	initial = min(max(initial, minLimit), maxLimit)
+	return &AdaptiveLimiter{limit: float64(initial), minLimit: float64(minLimit), maxLimit: float64(maxLimit), changed: make(chan struct{})}
+}
+
+// Limit 当前允许的在途请求数上限
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// InFlight 当前在途的请求数
+func (l *AdaptiveLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// acquire 等待一个名额, 请求结束后用请求结果调用 release 归还并调整上限
+func (l *AdaptiveLimiter) acquire(ctx context.Context) (release func(httpStatusCode int, err error), err error) {
+	for {
+		l.mu.Lock()
+		if l.inFlight < int(l.limit) {
+			l.inFlight++
+			l.mu.Unlock()
+			return l.release, nil
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (l *AdaptiveLimiter) release(httpStatusCode int, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	switch {
+	case isOverload(httpStatusCode, err):
+		l.limit = max(l.limit/2, l.minLimit)
+	case httpStatusCode != 0: // 后端正常响应了, 包括 4xx
+		l.limit = min(l.limit+1/l.limit, l.maxLimit)
+	}
+	// 被调用方取消的请求不代表后端的状态, 不调整上限
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// isOverload 请求结果是否说明后端过载: 5xx、超时或连接失败
+func isOverload(httpStatusCode int, err error) bool {
+	if httpStatusCode >= http.StatusInternalServerError {
+		return true
+	}
+	if err == nil || httpStatusCode != 0 || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr)
+}
+
+// WithAdaptiveLimit 请求经过自适应限流器, 在途请求数达到上限时等待名额, 等待时会响应请求上下文的取消
+func WithAdaptiveLimit(limiter *AdaptiveLimiter) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.adaptiveLimiter = limiter
+		return
+	})
+}
+
+// acquireAdaptive 设置了 WithAdaptiveLimit 时获取名额
+func (opts *requestOption) acquireAdaptive() (release func(httpStatusCode int, err error), err error) {
+	if opts.adaptiveLimiter == nil {
+		return func(int, error) {}, nil
+	}
+	return opts.adaptiveLimiter.acquire(opts.ctx)
+}
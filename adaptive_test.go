@@ -0,0 +1,95 @@
+package httptool
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestAdaptiveLimiter 测试 AIMD 调整上限
+func TestAdaptiveLimiter(t *testing.T) {
+	l := NewAdaptiveLimiter(4, 1, 8)
+	ctx := context.Background()
+
+	release, err := l.acquire(ctx)
+	if err != nil {
+		t.Fatalf("获取名额失败: %v", err)
+	}
+	release(http.StatusServiceUnavailable, nil)
+	if l.Limit() != 2 {
+		t.Fatalf("5xx 后期望上限减半为 2, 得到 %d", l.Limit())
+	}
+
+	for i := 0; i < 20; i++ {
+		release, _ := l.acquire(ctx)
+		release(http.StatusOK, nil)
+	}
+	if got := l.Limit(); got <= 2 || got > 8 {
+		t.Fatalf("成功后期望上限增加且不超过 8, 得到 %d", got)
+	}
+
+	for i := 0; i < 10; i++ {
+		release, _ := l.acquire(ctx)
+		release(0, context.DeadlineExceeded)
+	}
+	if l.Limit() != 1 {
+		t.Fatalf("超时后期望上限降到下限 1, 得到 %d", l.Limit())
+	}
+
+	release, _ = l.acquire(ctx)
+	release(0, context.Canceled)
+	if l.Limit() != 1 || l.InFlight() != 0 {
+		t.Fatalf("取消的请求不应调整上限, 得到上限 %d 在途 %d", l.Limit(), l.InFlight())
+	}
+
+	// 名额用完时等待, 响应上下文的取消
+	release, _ = l.acquire(ctx)
+	defer release(http.StatusOK, nil)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(timeoutCtx); err == nil {
+		t.Fatal("名额用完时期望等待到上下文超时")
+	}
+}
+
+// TestWithAdaptiveLimit 测试请求经过自适应限流器
+func TestWithAdaptiveLimit(t *testing.T) {
+	ResetDefaultClient()
+
+	var current, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		defer current.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	GetHttpClient() // 先初始化全局客户端, 本用例只关注在途请求数
+	limiter := NewAdaptiveLimiter(2, 1, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Get(context.Background(), server.URL, WithAdaptiveLimit(limiter))
+		}()
+	}
+	wg.Wait()
+	if peak.Load() > 2 {
+		t.Fatalf("在途请求数不应超过初始上限 2, 得到 %d", peak.Load())
+	}
+	if limiter.Limit() != 1 || limiter.InFlight() != 0 {
+		t.Fatalf("503 后期望上限降到 1 且名额全部归还, 得到上限 %d 在途 %d", limiter.Limit(), limiter.InFlight())
+	}
+}
@@ -0,0 +1,37 @@
+package httptool
+
+import (
+	"encoding/base64"
+	"net/http"
+)
+
+// WithBasicAuth 设置 HTTP Basic 认证, 即 Authorization: Basic base64(username:password)
+// 优先于 WithHeaders 设置的 Authorization, 与选项的先后顺序无关
+func WithBasicAuth(username, password string) Option {
+	authorization := "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.authorization = authorization
+		return
+	})
+}
+
+// WithBearerToken 设置 Authorization: Bearer <token>, 优先于 WithHeaders 设置的 Authorization, 与选项的先后顺序无关
+func WithBearerToken(token string) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.authorization = "Bearer " + token
+		return
+	})
+}
+
+// applyAuthorization 在全部选项应用完之后把认证信息合并到请求头, 替换其他方式设置的 Authorization(不区分大小写)
+func (opts *requestOption) applyAuthorization() {
+	if opts.authorization == "" {
+		return
+	}
+	for k := range opts.headers {
+		if http.CanonicalHeaderKey(k) == "Authorization" {
+			delete(opts.headers, k)
+		}
+	}
+	opts.headers["Authorization"] = opts.authorization
+}
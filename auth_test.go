@@ -0,0 +1,49 @@
+package httptool
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestAuthOptions 测试 Basic 认证和 Bearer Token
+func TestAuthOptions(t *testing.T) {
+	ResetDefaultClient()
+
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	t.Run("Basic认证", func(t *testing.T) {
+		if _, _, err := Get(ctx, server.URL, WithBasicAuth("user", "p@ss:word")); err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		req.Header = got
+		username, password, ok := req.BasicAuth()
+		if !ok || username != "user" || password != "p@ss:word" {
+			t.Fatalf("期望 Basic 认证 user/p@ss:word, 得到 %q", got.Get("Authorization"))
+		}
+	})
+
+	t.Run("优先于WithHeaders", func(t *testing.T) {
+		headers := WithHeaders(map[string]string{"authorization": "Bearer old", "X-Other": "1"})
+		if _, _, err := Get(ctx, server.URL, WithBearerToken("new"), headers); err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		if values := got.Values("Authorization"); len(values) != 1 || values[0] != "Bearer new" || got.Get("X-Other") != "1" {
+			t.Fatalf("期望 Authorization 只有 Bearer new, 得到 %v", got)
+		}
+	})
+
+	t.Run("满足必填请求头", func(t *testing.T) {
+		if _, _, err := Get(ctx, server.URL, WithRequireHeaders("Authorization"), WithBearerToken("token")); err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+	})
+}
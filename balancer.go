@@ -0,0 +1,96 @@
+package httptool
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BalanceStrategy 负载均衡策略
+type BalanceStrategy int
+
+const (
+	// RoundRobin 轮询
+	RoundRobin BalanceStrategy = iota
+	// Random 随机
+	Random
+)
+
+// Balancer 客户端负载均衡器, 每次请求从 hosts 中选出一个替换请求URL中的 host
+// 请求失败(连接错误或 5xx)的 host 在 cooldown 时间内会被跳过, 全部不可用时仍然按策略选取
+type Balancer struct {
+	hosts    []string
+	strategy BalanceStrategy
+	cooldown time.Duration
+	next     atomic.Uint64
+
+	mu        sync.Mutex
+	downUntil map[string]time.Time // 被标记为失败的 host 恢复可用的时间
+}
+
+// NewBalancer 创建负载均衡器, hosts 为 host 或 host:port 形式, 不包含 scheme
+func NewBalancer(strategy BalanceStrategy, cooldown time.Duration, hosts ...string) *Balancer {
+	return &Balancer{
+		hosts:     hosts,
+		strategy:  strategy,
+		cooldown:  cooldown,
+		downUntil: map[string]time.Time{},
+	}
+}
+
+// pick 选出本次请求使用的 host
+func (b *Balancer) pick() string {
+	var start int
+	if b.strategy == Random {
+		start = rand.IntN(len(b.hosts))
+	} else {
+		start = int((b.next.Add(1) - 1) % uint64(len(b.hosts)))
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	for i := range b.hosts {
+		host := b.hosts[(start+i)%len(b.hosts)]
+		if now.After(b.downUntil[host]) {
+			return host
+		}
+	}
+	return b.hosts[start]
+}
+
+// report 上报请求结果, 失败的 host 在 cooldown 时间内被跳过
+func (b *Balancer) report(host string, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if failed {
+		b.downUntil[host] = time.Now().Add(b.cooldown)
+	} else {
+		delete(b.downUntil, host)
+	}
+}
+
+// rewrite 把 rawURL 中的 host 替换为选出的 host
+func (b *Balancer) rewrite(rawURL string) (newURL string, host string, err error) {
+	if len(b.hosts) == 0 {
+		return "", "", errors.New("balancer has no hosts")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	host = b.pick()
+	u.Host = host
+	return u.String(), host, nil
+}
+
+// WithBalancer 使用负载均衡器选择请求的 host
+func WithBalancer(b *Balancer) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.balancer, err = b, nil
+		return
+	})
+}
@@ -0,0 +1,70 @@
+package httptool
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestBalancerRoundRobin 测试轮询负载均衡
+func TestBalancerRoundRobin(t *testing.T) {
+	ResetDefaultClient()
+
+	newServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(name))
+		}))
+	}
+	s1, s2 := newServer("s1"), newServer("s2")
+	defer s1.Close()
+	defer s2.Close()
+
+	b := NewBalancer(RoundRobin, time.Minute, strings.TrimPrefix(s1.URL, "http://"), strings.TrimPrefix(s2.URL, "http://"))
+	ctx := context.Background()
+	var got []string
+	for i := 0; i < 4; i++ {
+		_, body, err := Get(ctx, "http://placeholder/path", WithBalancer(b))
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		got = append(got, string(body))
+	}
+	if strings.Join(got, ",") != "s1,s2,s1,s2" {
+		t.Fatalf("期望轮询顺序 s1,s2,s1,s2, 得到 %v", got)
+	}
+}
+
+// TestBalancerSkipFailedHost 测试跳过失败的 host
+func TestBalancerSkipFailedHost(t *testing.T) {
+	ResetDefaultClient()
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer broken.Close()
+
+	brokenHost := strings.TrimPrefix(broken.URL, "http://")
+	b := NewBalancer(RoundRobin, time.Minute, brokenHost, strings.TrimPrefix(healthy.URL, "http://"))
+	ctx := context.Background()
+
+	// 第一次命中失败的 host, 之后在 cooldown 内应一直跳过它
+	if _, _, err := Get(ctx, "http://placeholder/", WithBalancer(b)); err == nil {
+		t.Fatal("第一次请求期望失败")
+	}
+	for i := 0; i < 3; i++ {
+		if _, _, err := Get(ctx, "http://placeholder/", WithBalancer(b)); err != nil {
+			t.Fatalf("失败的 host 应被跳过, 得到 %v", err)
+		}
+	}
+	if host := b.pick(); host == brokenHost {
+		t.Fatal("cooldown 内不应选中失败的 host")
+	}
+}
@@ -0,0 +1,413 @@
+package httptool
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"sync/atomic"
+	"text/template"
+)
+
+// WithFileBody 使用文件内容作为请求体, 按文件大小设置 Content-Length
+// 每次发送都从文件开头重新读取(并设置 GetBody), 重定向或重试时可以安全地重发请求体; 请求结束前不要关闭文件
+func WithFileBody(f *os.File) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		size := info.Size()
+		opts.bodyFunc = func() (io.Reader, int64, error) {
+			return io.NewSectionReader(f, 0, size), size, nil
+		}
+		opts.bodyNotReplayable, opts.bodySharedSeeker = false, false
+		return
+	})
+}
+
+// ErrBodyNotReplayable WithBodyReader 传入的 Reader 不支持 Seek, 请求体只能发送一次, 无法重发时返回
+var ErrBodyNotReplayable = errors.New("request body reader cannot be replayed")
+
+// WithBodyReader 从 r 读取请求体, 不需要先把大的请求体整个读进内存, 与 WithData 同时设置时以 WithBodyReader 为准
+// r 实现了 io.ReadSeeker 时按剩余长度设置 Content-Length, 每次发送前回到开始的位置, 可以安全地重试;
+// 同时实现了 io.ReaderAt 时(如 *os.File、*bytes.Reader)各次发送互不影响, 也可以用于 WithHedging; 只实现了 io.ReadSeeker 时
+// 各次发送共用同一个读取位置, 不能同时发送, 与 WithHedging 一起使用时返回错误
+// 其他 Reader 使用分块传输, 只能发送一次: WithRetry 不会重试, 需要重发(备用地址、对冲请求)时返回 ErrBodyNotReplayable, 也不能与 WithIdempotencyKey 一起使用
+func WithBodyReader(r io.Reader) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		seeker, ok := r.(io.ReadSeeker)
+		if !ok {
+			var used atomic.Bool // 每次应用选项重新计数, 选项被多个请求复用时各自发送一次
+			opts.bodyFunc = func() (io.Reader, int64, error) {
+				if used.Swap(true) {
+					return nil, 0, ErrBodyNotReplayable
+				}
+				return r, -1, nil
+			}
+			opts.bodyNotReplayable, opts.bodySharedSeeker = true, false
+			return
+		}
+
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+		if _, err = seeker.Seek(start, io.SeekStart); err != nil {
+			return err
+		}
+		size := end - start
+		readerAt, ok := r.(io.ReaderAt)
+		if ok {
+			opts.bodyFunc = func() (io.Reader, int64, error) {
+				return io.NewSectionReader(readerAt, start, size), size, nil
+			}
+		} else {
+			opts.bodyFunc = func() (io.Reader, int64, error) {
+				if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+					return nil, 0, err
+				}
+				return seeker, size, nil
+			}
+		}
+		opts.bodyNotReplayable, opts.bodySharedSeeker = false, !ok
+		return
+	})
+}
+
+// WithBodyTemplate 执行模板生成请求体, 用于 SOAP、GraphQL 这类结构固定、只有部分字段变化的请求体
+// 模板在创建请求时执行一次, 执行失败时请求不会发出并返回模板的错误; text/template 不做转义, 需要转义的字段请在模板中用函数处理
+func WithBodyTemplate(tmpl *template.Template, data any) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return fmt.Errorf("execute body template: %w", err)
+		}
+		opts.data = buf.Bytes()
+		return
+	})
+}
+
+// newRequest 创建请求对象, 设置了 WithFileBody 等选项时使用对应的请求体
+func (opts *requestOption) newRequest(method string, url string) (*http.Request, error) {
+	if opts.bodyFunc == nil {
+		return http.NewRequest(method, url, bytes.NewReader(opts.data))
+	}
+
+	body, contentLength, err := opts.bodyFunc()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = contentLength
+	if contentLength == 0 {
+		req.Body = http.NoBody
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		body, _, err := opts.bodyFunc()
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(body), nil
+	}
+	return req, nil
+}
+
+// WithRequestTrailer 声明请求的 trailer, 请求体全部发送之后调用 fill 填充 trailer 的值, 如边上传边计算的校验和
+// trailer 只能通过分块传输编码发送, 设置后请求不再带 Content-Length; fill 只能设置 keys 中声明过的 trailer
+func WithRequestTrailer(fill func(trailer http.Header), keys ...string) Option {
+	keys = slices.Clone(keys)
+	return optionFunc(func(opts *requestOption) (err error) {
+		if len(keys) == 0 {
+			return errors.New("request trailer requires at least one key")
+		}
+		opts.trailerKeys, opts.trailerFill = keys, fill
+		return
+	})
+}
+
+// setTrailer 设置了 WithRequestTrailer 时改为分块发送请求体, 读完请求体后填充 trailer
+func (opts *requestOption) setTrailer(req *http.Request) {
+	if len(opts.trailerKeys) == 0 {
+		return
+	}
+	req.Trailer = make(http.Header, len(opts.trailerKeys))
+	for _, key := range opts.trailerKeys {
+		req.Trailer[http.CanonicalHeaderKey(key)] = nil
+	}
+	req.ContentLength = -1 // Transport 写完请求体之后才输出 trailer, 这时 fill 已经执行
+	req.Body = &trailerBody{ReadCloser: req.Body, fill: func() { opts.trailerFill(req.Trailer) }}
+}
+
+// trailerBody 请求体读到末尾时填充 trailer
+type trailerBody struct {
+	io.ReadCloser
+	fill   func()
+	filled bool
+}
+
+func (t *trailerBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if err == io.EOF && !t.filled {
+		t.filled = true
+		t.fill()
+	}
+	return n, err
+}
+
+// WithBodyObserver 读取响应体时每读到一块数据就回调 observer, 可用于展示下载进度或增量处理
+// 请求仍然返回完整的响应体; chunk 的底层数组会被复用, 回调返回后如需保留请自行拷贝
+// 读取过程中请求上下文被取消时停止读取并返回上下文的错误
+func WithBodyObserver(observer func(chunk []byte)) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.bodyObserver, err = observer, nil
+		return
+	})
+}
+
+// ErrBodyTooLarge 响应体超过 WithMaxResponseBytes 设置的上限时返回
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// WithMaxResponseBytes 限制响应体的最大字节数(解压后), 超过时丢弃响应体并返回 ErrBodyTooLarge
+// 响应声明的 Content-Length 已经超过上限时不读取响应体直接失败; 没有 Content-Length 的响应读到超过上限为止
+func WithMaxResponseBytes(n int64) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		if n <= 0 {
+			return fmt.Errorf("max response bytes must be positive, got %d", n)
+		}
+		opts.maxResponseBytes = n
+		return
+	})
+}
+
+// ErrContentLengthMismatch 开启 WithValidateContentLength 后实际读到的响应体字节数与 Content-Length 不一致时返回
+var ErrContentLengthMismatch = errors.New("response body length does not match Content-Length")
+
+// WithValidateContentLength 校验实际读到的响应体字节数与响应声明的 Content-Length 一致, 不一致时返回 ErrContentLengthMismatch
+// 用于发现代理或连接中断导致的响应体被截断; 分块传输等没有 Content-Length 的响应、HEAD 请求和 Transport 自动解压的响应不校验
+// 按压缩后的字节数校验, 与 Content-Encoding 无关
+func WithValidateContentLength() Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.validateContentLength = true
+		return
+	})
+}
+
+// checkContentLength 在读取响应体之前按声明的 Content-Length 检查响应体大小, 需要时在响应体上加上长度校验
+func (opts *requestOption) checkContentLength(resp *http.Response) error {
+	if opts.maxResponseBytes > 0 && resp.ContentLength > opts.maxResponseBytes {
+		return fmt.Errorf("%w: Content-Length %d exceeds limit %d", ErrBodyTooLarge, resp.ContentLength, opts.maxResponseBytes)
+	}
+	if opts.validateContentLength && resp.ContentLength >= 0 && (resp.Request == nil || resp.Request.Method != http.MethodHead) {
+		resp.Body = contentLengthBody{
+			Reader: &contentLengthReader{r: resp.Body, remaining: resp.ContentLength, declared: resp.ContentLength},
+			Closer: resp.Body,
+		}
+	}
+	return nil
+}
+
+type contentLengthBody struct {
+	io.Reader
+	io.Closer
+}
+
+// contentLengthReader 读到结尾时检查读到的字节数是否等于 Content-Length
+type contentLengthReader struct {
+	r         io.Reader
+	remaining int64
+	declared  int64
+}
+
+func (c *contentLengthReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	if c.remaining < 0 {
+		return n, fmt.Errorf("%w: read more than %d bytes", ErrContentLengthMismatch, c.declared)
+	}
+	if c.remaining > 0 && (err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF)) {
+		return n, fmt.Errorf("%w: read %d of %d bytes: %w", ErrContentLengthMismatch, c.declared-c.remaining, c.declared, err)
+	}
+	return n, err
+}
+
+// readBody 读取完整的响应体, 设置了 WithCompressToFile 或流式处理响应体时返回的响应体为nil
+func (opts *requestOption) readBody(r io.Reader) ([]byte, error) {
+	if opts.maxResponseBytes > 0 {
+		r = &maxBytesReader{r: r, remaining: opts.maxResponseBytes, limit: opts.maxResponseBytes}
+	}
+	if opts.bodyReadLimit > 0 {
+		truncated := &truncatingReader{r: r, remaining: opts.bodyReadLimit}
+		if opts.stats != nil {
+			defer func() { opts.stats.Truncated = truncated.truncated }()
+		}
+		r = truncated
+	}
+	if opts.bodyObserver != nil || opts.bodyConsumer != nil {
+		opts.bodyHandedOut = true
+	}
+	if opts.bodyObserver != nil {
+		r = &observedReader{ctx: opts.ctx, r: r, observer: opts.bodyObserver}
+	}
+	if opts.bodyConsumer != nil {
+		return nil, opts.bodyConsumer(opts.ctx, r)
+	}
+	if opts.compressToFile != "" {
+		return nil, opts.compressBodyToFile(r)
+	}
+	if opts.bodyPool {
+		return readPooled(r)
+	}
+	return io.ReadAll(r)
+}
+
+// readErrorBody 读取非 2xx 响应的响应体, 只应用大小限制, 不交给流式处理或写入文件; 读取失败时返回 nil
+func (opts *requestOption) readErrorBody(r io.Reader) []byte {
+	if opts.maxResponseBytes > 0 {
+		r = &maxBytesReader{r: r, remaining: opts.maxResponseBytes, limit: opts.maxResponseBytes}
+	}
+	if opts.bodyReadLimit > 0 {
+		r = &truncatingReader{r: r, remaining: opts.bodyReadLimit}
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil
+	}
+	return body
+}
+
+// WithBodyReadLimit 最多读取响应体的前 n 个字节, 超出的部分直接丢弃且不返回错误, 适合只需要预览响应体的场景(如错误页记日志)
+// 是否发生截断通过 WithStats 的 Stats.Truncated 获取; 需要超限时报错请使用 WithMaxResponseBytes
+func WithBodyReadLimit(n int64) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		if n <= 0 {
+			return fmt.Errorf("body read limit must be positive, got %d", n)
+		}
+		opts.bodyReadLimit = n
+		return
+	})
+}
+
+// truncatingReader 读到上限后返回 EOF, 并记录后面是否还有数据
+type truncatingReader struct {
+	r         io.Reader
+	remaining int64
+	truncated bool
+}
+
+func (t *truncatingReader) Read(p []byte) (int, error) {
+	if t.remaining <= 0 {
+		var probe [1]byte // 多读一个字节判断响应体是否真的被截断
+		if n, _ := io.ReadFull(t.r, probe[:]); n > 0 {
+			t.truncated = true
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > t.remaining {
+		p = p[:t.remaining]
+	}
+	n, err := t.r.Read(p)
+	t.remaining -= int64(n)
+	return n, err
+}
+
+// maxBytesReader 读到超过上限的数据时返回 ErrBodyTooLarge
+type maxBytesReader struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+}
+
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > m.remaining+1 {
+		p = p[:m.remaining+1] // 多读一个字节用来判断是否超过上限
+	}
+	n, err := m.r.Read(p)
+	if int64(n) > m.remaining {
+		return int(m.remaining), fmt.Errorf("%w: exceeds limit %d", ErrBodyTooLarge, m.limit)
+	}
+	m.remaining -= int64(n)
+	return n, err
+}
+
+// observedReader 把读到的每块数据回调给 WithBodyObserver 设置的函数, 上下文结束后停止读取
+type observedReader struct {
+	ctx      context.Context
+	r        io.Reader
+	observer func(chunk []byte)
+}
+
+func (o *observedReader) Read(p []byte) (int, error) {
+	if err := o.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := o.r.Read(p)
+	if n > 0 {
+		o.observer(p[:n])
+	}
+	return n, err
+}
+
+// WithCompressToFile 把响应体经过 gzip 压缩后流式写入 path, 不在内存中保留响应体, 也不在磁盘上保留未压缩的内容
+// 适合下载日志、备份这类压缩率高的大文件; 请求返回的响应体为nil, 失败时删除写了一半的文件
+// 配合 WithStats 可以拿到压缩前后的字节数
+func WithCompressToFile(path string) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.compressToFile, err = path, nil
+		return
+	})
+}
+
+// compressBodyToFile 把响应体压缩写入 WithCompressToFile 指定的文件
+func (opts *requestOption) compressBodyToFile(r io.Reader) (err error) {
+	f, err := os.Create(opts.compressToFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(opts.compressToFile)
+		}
+	}()
+
+	compressed := &countingWriter{w: f}
+	zw := gzip.NewWriter(compressed)
+	uncompressed, err := io.Copy(zw, r)
+	if err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	if opts.stats != nil {
+		opts.stats.UncompressedBytes, opts.stats.CompressedBytes = uncompressed, compressed.n
+	}
+	return nil
+}
+
+// countingWriter 统计写入的字节数
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,409 @@
+package httptool
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"text/template"
+	"time"
+)
+
+// TestWithBodyObserver 测试逐块回调响应体
+func TestWithBodyObserver(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 3; i++ {
+			w.Write([]byte("chunk;"))
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	t.Run("逐块回调", func(t *testing.T) {
+		var observed strings.Builder
+		calls := 0
+		_, body, err := Get(context.Background(), server.URL, WithBodyObserver(func(chunk []byte) {
+			calls++
+			observed.Write(chunk)
+		}))
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		if string(body) != "chunk;chunk;chunk;" || observed.String() != string(body) {
+			t.Fatalf("回调内容与响应体不一致, 回调 %q, 响应体 %q", observed.String(), string(body))
+		}
+		if calls < 2 {
+			t.Fatalf("期望多次回调, 得到 %d 次", calls)
+		}
+	})
+
+	t.Run("取消后停止读取", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		_, body, err := Get(ctx, server.URL, WithBodyObserver(func(chunk []byte) {
+			cancel()
+		}))
+		if err == nil || body != nil {
+			t.Fatalf("取消后期望返回错误且不返回响应体, 得到 %v %q", err, string(body))
+		}
+	})
+}
+
+// TestWithFileBody 测试使用文件作为请求体
+func TestWithFileBody(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.ContentLength != int64(len("file content")) || string(body) != "file content" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	f, err := os.CreateTemp(t.TempDir(), "upload_*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.WriteString("file content") // 写入后读取位置在文件末尾, 请求体仍应从头读取
+
+	opts := defaultRequestOptions()
+	if err := WithFileBody(f).apply(opts); err != nil {
+		t.Fatalf("WithFileBody应用失败: %v", err)
+	}
+	req, err := opts.newRequest("PUT", server.URL)
+	if err != nil {
+		t.Fatalf("创建请求失败: %v", err)
+	}
+	io.ReadAll(req.Body)
+	body, err := req.GetBody()
+	if err != nil {
+		t.Fatalf("GetBody失败: %v", err)
+	}
+	if content, _ := io.ReadAll(body); string(content) != "file content" {
+		t.Fatalf("GetBody 应返回完整的请求体, 得到 %q", string(content))
+	}
+
+	statusCode, _, err := Request("PUT", server.URL, WithFileBody(f))
+	if err != nil || statusCode != http.StatusOK {
+		t.Fatalf("上传失败: %d %v", statusCode, err)
+	}
+}
+
+// TestWithBodyReader 测试从 Reader 读取请求体
+func TestWithBodyReader(t *testing.T) {
+	ResetDefaultClient()
+
+	var requests atomic.Int32
+	var gotLength int64
+	var gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotLength, gotBody = r.ContentLength, string(body)
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	retry := WithRetry(2, time.Millisecond)
+
+	t.Run("可以Seek的Reader", func(t *testing.T) {
+		requests.Store(0)
+		r := strings.NewReader("skip:payload")
+		r.Seek(5, io.SeekStart) // 从当前位置开始发送
+		_, _, err := Request("PUT", server.URL, WithData([]byte("ignored")), WithBodyReader(r), retry)
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		if requests.Load() != 2 || gotLength != int64(len("payload")) || gotBody != "payload" {
+			t.Fatalf("期望重试后发送完整的请求体, 得到第 %d 次 %d %q", requests.Load(), gotLength, gotBody)
+		}
+	})
+
+	t.Run("不能Seek的Reader", func(t *testing.T) {
+		requests.Store(0)
+		r := io.MultiReader(strings.NewReader("stream"))
+		_, _, err := Request("PUT", server.URL, WithBodyReader(r), retry)
+		if err == nil || requests.Load() != 1 {
+			t.Fatalf("只能发送一次的请求体不应重试, 得到第 %d 次 %v", requests.Load(), err)
+		}
+		if gotLength != -1 || gotBody != "stream" {
+			t.Fatalf("期望分块发送请求体, 得到 %d %q", gotLength, gotBody)
+		}
+		_, _, err = Request("PUT", server.URL, WithBodyReader(r), WithIdempotencyKey("op"))
+		if !errors.Is(err, ErrBodyNotReplayable) {
+			t.Fatalf("期望 ErrBodyNotReplayable, 得到 %v", err)
+		}
+	})
+}
+
+// TestWithValidateContentLength 测试校验响应体长度
+func TestWithValidateContentLength(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/truncated": // 声明的 Content-Length 大于实际写入的长度, 模拟连接中途断开
+			w.Header().Set("Content-Length", "100")
+			w.Write([]byte(`{"partial":`))
+		case "/chunked":
+			w.Write([]byte("chunk"))
+			w.(http.Flusher).Flush()
+			w.Write([]byte("ed"))
+		default:
+			w.Write([]byte("complete"))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	_, body, err := Get(ctx, server.URL+"/truncated", WithValidateContentLength())
+	if !errors.Is(err, ErrContentLengthMismatch) || !errors.Is(err, io.ErrUnexpectedEOF) || body != nil {
+		t.Fatalf("期望 ErrContentLengthMismatch, 得到 %q %v", string(body), err)
+	}
+
+	for path, want := range map[string]string{"/": "complete", "/chunked": "chunked"} {
+		if _, body, err := Get(ctx, server.URL+path, WithValidateContentLength()); err != nil || string(body) != want {
+			t.Fatalf("%s 期望 %q, 得到 %q %v", path, want, string(body), err)
+		}
+	}
+	if _, _, err := Head(ctx, server.URL, WithValidateContentLength()); err != nil {
+		t.Fatalf("HEAD 请求不应校验响应体长度: %v", err)
+	}
+}
+
+// TestDeadlineAwareBody 测试读取响应体不会超过上下文的 deadline
+func TestDeadlineAwareBody(t *testing.T) {
+	ResetDefaultClient()
+	defer ResetDefaultClient()
+
+	// 服务端缓慢地逐字节返回响应体
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 20; i++ {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(50 * time.Millisecond):
+			}
+			w.Write([]byte("."))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	check := func(t *testing.T, url string) {
+		start := time.Now()
+		_, body, err := Get(context.Background(), url, WithTimeout(150*time.Millisecond))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("期望错误 %v, 得到 %v", context.DeadlineExceeded, err)
+		}
+		if body != nil {
+			t.Fatalf("超时时不应返回部分响应体, 得到 %q", string(body))
+		}
+		if dur := time.Since(start); dur > 500*time.Millisecond {
+			t.Fatalf("读取响应体超过了 deadline, 耗时 %v", dur)
+		}
+	}
+
+	t.Run("缓慢的服务端", func(t *testing.T) {
+		check(t, server.URL)
+	})
+
+	// 不响应上下文的 RoundTripper, 响应体的读取会一直阻塞
+	t.Run("不响应上下文的RoundTripper", func(t *testing.T) {
+		SetHttpClient(&http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			pr, _ := io.Pipe()
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: pr, Request: r}, nil
+		})})
+		check(t, "http://example.com")
+	})
+}
+
+// TestWithCompressToFile 测试响应体压缩写入文件
+func TestWithCompressToFile(t *testing.T) {
+	ResetDefaultClient()
+
+	content := strings.Repeat("2024-01-01 INFO request handled\n", 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "app.log.gz")
+	var stats Stats
+	_, body, err := Get(context.Background(), server.URL, WithCompressToFile(path), WithStats(&stats))
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if body != nil {
+		t.Fatal("写入文件时不应返回响应体")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("文件不是合法的 gzip: %v", err)
+	}
+	decompressed, _ := io.ReadAll(zr)
+	if string(decompressed) != content {
+		t.Fatal("解压后的内容与响应体不一致")
+	}
+
+	info, _ := f.Stat()
+	if stats.UncompressedBytes != int64(len(content)) || stats.CompressedBytes != info.Size() {
+		t.Fatalf("字节数统计不正确: %+v, 文件大小 %d", stats, info.Size())
+	}
+	if stats.CompressedBytes >= stats.UncompressedBytes {
+		t.Fatalf("压缩后应更小: %+v", stats)
+	}
+}
+
+// TestWithMaxResponseBytes 测试响应体大小限制
+func TestWithMaxResponseBytes(t *testing.T) {
+	ResetDefaultClient()
+
+	body := strings.Repeat("x", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("chunked") == "" {
+			w.Header().Set("Content-Length", "100")
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		url     string
+		limit   int64
+		wantErr bool
+	}{
+		{"Content-Length 超过上限", server.URL, 50, true},
+		{"无 Content-Length 超过上限", server.URL + "?chunked=1", 50, true},
+		{"恰好等于上限", server.URL + "?chunked=1", 100, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, respBody, err := Get(ctx, tt.url, WithMaxResponseBytes(tt.limit))
+			if tt.wantErr {
+				if !errors.Is(err, ErrBodyTooLarge) || respBody != nil {
+					t.Fatalf("期望 ErrBodyTooLarge 且不返回响应体, 得到 %v %d 字节", err, len(respBody))
+				}
+				return
+			}
+			if err != nil || string(respBody) != body {
+				t.Fatalf("期望完整读取响应体, 得到 %v %d 字节", err, len(respBody))
+			}
+		})
+	}
+
+	if _, _, err := Get(ctx, server.URL, WithMaxResponseBytes(0)); err == nil {
+		t.Fatal("上限为 0 时期望返回配置错误")
+	}
+}
+
+// TestWithRequestTrailer 测试请求体之后发送 trailer
+func TestWithRequestTrailer(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body) // trailer 在读完请求体之后才可用
+		if len(r.TransferEncoding) == 0 || r.TransferEncoding[0] != "chunked" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(string(body) + "|" + r.Trailer.Get("X-Checksum")))
+	}))
+	defer server.Close()
+
+	_, body, err := Post(context.Background(), server.URL, []byte("payload"), WithRequestTrailer(func(trailer http.Header) {
+		trailer.Set("X-Checksum", "sum-7")
+	}, "X-Checksum"))
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if string(body) != "payload|sum-7" {
+		t.Fatalf("期望服务端收到请求体和 trailer, 得到 %q", string(body))
+	}
+}
+
+// TestWithBodyReadLimit 测试截断响应体
+func TestWithBodyReadLimit(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("0123456789"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		limit         int64
+		wantBody      string
+		wantTruncated bool
+	}{
+		{4, "0123", true},
+		{10, "0123456789", false},
+		{20, "0123456789", false},
+	}
+	for _, tt := range tests {
+		var stats Stats
+		_, body, err := Get(context.Background(), server.URL, WithBodyReadLimit(tt.limit), WithStats(&stats))
+		if err != nil {
+			t.Fatalf("截断不应返回错误, 得到 %v", err)
+		}
+		if string(body) != tt.wantBody || stats.Truncated != tt.wantTruncated {
+			t.Fatalf("上限 %d: 期望 %q 截断=%v, 得到 %q 截断=%v", tt.limit, tt.wantBody, tt.wantTruncated, string(body), stats.Truncated)
+		}
+	}
+}
+
+// TestWithBodyTemplate 测试使用模板生成请求体
+func TestWithBodyTemplate(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	tmpl := template.Must(template.New("soap").Parse(`<GetUser><Id>{{.ID}}</Id></GetUser>`))
+	_, body, err := Post(context.Background(), server.URL, nil, WithBodyTemplate(tmpl, map[string]int{"ID": 42}))
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if string(body) != "<GetUser><Id>42</Id></GetUser>" {
+		t.Fatalf("请求体不正确, 得到 %q", string(body))
+	}
+
+	bad := template.Must(template.New("bad").Parse(`{{.Missing.Field}}`))
+	if _, _, err := Post(context.Background(), server.URL, nil, WithBodyTemplate(bad, struct{}{})); err == nil {
+		t.Fatal("模板执行失败时期望返回错误")
+	}
+}
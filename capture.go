@@ -0,0 +1,52 @@
+package httptool
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ResponseInfo 请求拿到的响应的元信息
+type ResponseInfo struct {
+	StatusCode int
+	Header     http.Header
+	Duration   time.Duration // 从发起请求到读完响应体的耗时
+}
+
+type responseCaptureKey struct{}
+
+// responseCapture 保存在上下文中的响应元信息, 请求完成后写入
+type responseCapture struct {
+	mu   sync.Mutex
+	info *ResponseInfo
+}
+
+// ContextWithResponseCapture 返回一个可以记录响应元信息的上下文, 用它发起的请求完成后可通过 ResponseFromContext 读取
+// 用于在更大的请求处理流水线中, 让下游的中间件在事后记录或处理对外调用的结果
+// 同一个上下文发起多次请求(包括备用地址)时保存的是最后一次拿到的响应
+func ContextWithResponseCapture(ctx context.Context) context.Context {
+	return context.WithValue(ctx, responseCaptureKey{}, &responseCapture{})
+}
+
+// ResponseFromContext 读取 ContextWithResponseCapture 记录的响应元信息, 还没有拿到响应时 ok 为 false
+func ResponseFromContext(ctx context.Context) (info *ResponseInfo, ok bool) {
+	c, _ := ctx.Value(responseCaptureKey{}).(*responseCapture)
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.info, c.info != nil
+}
+
+// captureResponse 上下文中有 responseCapture 时记录响应元信息
+func (opts *requestOption) captureResponse(httpStatusCode int, header http.Header, dur time.Duration) {
+	c, _ := opts.ctx.Value(responseCaptureKey{}).(*responseCapture)
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.info = &ResponseInfo{StatusCode: httpStatusCode, Header: header.Clone(), Duration: dur} // 复制一份, 调用方修改返回的响应头不影响这里
+}
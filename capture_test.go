@@ -0,0 +1,37 @@
+package httptool
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestResponseFromContext 测试通过上下文读取响应元信息
+func TestResponseFromContext(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-1")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	ctx := ContextWithResponseCapture(context.Background())
+	if _, ok := ResponseFromContext(ctx); ok {
+		t.Fatal("请求前不应有响应信息")
+	}
+	Get(ctx, server.URL)
+
+	info, ok := ResponseFromContext(ctx)
+	if !ok {
+		t.Fatal("请求后期望读到响应信息")
+	}
+	if info.StatusCode != http.StatusNotFound || info.Header.Get("X-Request-Id") != "req-1" || info.Duration <= 0 {
+		t.Fatalf("响应信息不正确: %+v", info)
+	}
+
+	if _, ok := ResponseFromContext(context.Background()); ok {
+		t.Fatal("没有开启记录的上下文不应有响应信息")
+	}
+}
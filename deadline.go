@@ -0,0 +1,54 @@
+package httptool
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithDeadlinePropagation 把请求上下文剩余的超时时间通过 headerName 请求头传给下游, 便于下游按剩余时间安排处理
+// headerName 为 grpc-timeout 时按 gRPC 的格式(如 1500m)发送, 否则发送剩余的毫秒数
+func WithDeadlinePropagation(headerName string) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.deadlinePropagationHeader, err = headerName, nil
+		return
+	})
+}
+
+// WithDeadlineHeader 把请求上下文的截止时间通过 headerName 请求头传给下游, 值为 Unix 毫秒时间戳, 如 X-Deadline: 1700000000000
+// 与 WithDeadlinePropagation 发送的剩余时间不同, 绝对时间不受请求在网络和队列中耽搁的影响, 但要求两端时钟基本同步
+// 上下文没有截止时间时不发送
+func WithDeadlineHeader(headerName string) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.deadlineHeader = headerName
+		return
+	})
+}
+
+// setDeadlineHeaders 在发送前根据上下文的 deadline 设置超时相关的请求头
+func (opts *requestOption) setDeadlineHeaders(req *http.Request) {
+	deadline, ok := opts.ctx.Deadline()
+	if !ok {
+		return
+	}
+	if opts.deadlinePropagationHeader != "" {
+		remaining := time.Until(deadline)
+		if remaining < 0 {
+			remaining = 0
+		}
+		req.Header.Set(opts.deadlinePropagationHeader, formatRemaining(opts.deadlinePropagationHeader, remaining))
+	}
+	if opts.deadlineHeader != "" {
+		req.Header.Set(opts.deadlineHeader, strconv.FormatInt(deadline.UnixMilli(), 10))
+	}
+}
+
+// formatRemaining 按请求头要求的格式格式化剩余时间
+func formatRemaining(headerName string, remaining time.Duration) string {
+	ms := remaining.Milliseconds()
+	if strings.EqualFold(headerName, "grpc-timeout") {
+		return strconv.FormatInt(ms, 10) + "m"
+	}
+	return strconv.FormatInt(ms, 10)
+}
@@ -0,0 +1,68 @@
+package httptool
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWithDeadlinePropagation 测试剩余超时时间通过请求头传递
+func TestWithDeadlinePropagation(t *testing.T) {
+	ResetDefaultClient()
+
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	t.Run("毫秒数", func(t *testing.T) {
+		_, _, err := Get(ctx, server.URL, WithTimeout(2*time.Second), WithDeadlinePropagation("X-Request-Deadline"))
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		ms, err := strconv.Atoi(got.Get("X-Request-Deadline"))
+		if err != nil || ms <= 0 || ms > 2000 {
+			t.Fatalf("期望剩余毫秒数在 (0, 2000] 之间, 得到 %q", got.Get("X-Request-Deadline"))
+		}
+	})
+
+	t.Run("grpc-timeout格式", func(t *testing.T) {
+		_, _, err := Get(ctx, server.URL, WithTimeout(2*time.Second), WithDeadlinePropagation("grpc-timeout"))
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		if v := got.Get("grpc-timeout"); !strings.HasSuffix(v, "m") {
+			t.Fatalf("期望 gRPC 格式的超时时间, 得到 %q", v)
+		}
+	})
+}
+
+// TestWithDeadlineHeader 测试截止时间通过请求头传递
+func TestWithDeadlineHeader(t *testing.T) {
+	ResetDefaultClient()
+
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-Deadline")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	deadline := time.Now().Add(time.Minute)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	if _, _, err := Get(ctx, server.URL, WithTimeout(time.Hour), WithDeadlineHeader("X-Deadline")); err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if want := strconv.FormatInt(deadline.UnixMilli(), 10); got != want {
+		t.Fatalf("期望截止时间 %s, 得到 %q", want, got)
+	}
+}
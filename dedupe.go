@@ -0,0 +1,83 @@
+package httptool
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var dedupeCalls = &dedupeGroup{calls: map[string]*dedupeCall{}}
+
+// dedupeGroup 记录时间窗口内的请求, key 相同的请求共用第一个请求的结果
+type dedupeGroup struct {
+	mu    sync.Mutex
+	calls map[string]*dedupeCall
+}
+
+type dedupeCall struct {
+	done           chan struct{} // 第一个请求完成后关闭
+	httpStatusCode int
+	header         http.Header
+	respBody       []byte
+	err            error
+	canceled       bool // 第一个请求因为它自己的上下文结束而失败, 结果不能给其他请求使用
+}
+
+// WithDedupeWindow 防止误操作(如双击)导致的重复请求: window 时间内 key 相同的请求只会真正发出第一个
+// 之后的请求等待第一个请求完成并返回它的结果, 等待时仍然受自己的超时和上下文控制
+// 第一个请求被它的调用方取消或超时时, 等待中且自己的上下文还没有结束的请求会重新发起(仍然只有一个真正发出)
+// 这是客户端的兜底, 对有副作用的操作仍建议配合服务端的幂等键(WithIdempotencyKey)
+func WithDedupeWindow(key string, window time.Duration) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		if key == "" || window <= 0 {
+			return fmt.Errorf("dedupe window requires a key and a positive window, got %q and %s", key, window)
+		}
+		opts.dedupeKey, opts.dedupeWindow = key, window
+		return
+	})
+}
+
+// sendDeduped 窗口内已经有相同 key 的请求时等待它的结果, 否则发起请求
+func (opts *requestOption) sendDeduped(method string, url string) (httpStatusCode int, header http.Header, respBody []byte, err error) {
+	dedupeCalls.mu.Lock()
+	if c, ok := dedupeCalls.calls[opts.dedupeKey]; ok {
+		dedupeCalls.mu.Unlock()
+		select {
+		case <-c.done:
+			if c.canceled && opts.ctx.Err() == nil {
+				return opts.sendDeduped(method, url)
+			}
+			// 拷贝一份, 避免多个调用方修改同一个响应体和响应头
+			return c.httpStatusCode, c.header.Clone(), bytes.Clone(c.respBody), c.err
+		case <-opts.ctx.Done():
+			return 0, nil, nil, opts.ctx.Err()
+		}
+	}
+	c := &dedupeCall{done: make(chan struct{})}
+	dedupeCalls.calls[opts.dedupeKey] = c
+	dedupeCalls.mu.Unlock()
+	// 窗口从第一个请求发出时开始计算, 到期后删除记录
+	key := opts.dedupeKey
+	time.AfterFunc(opts.dedupeWindow, func() {
+		dedupeCalls.mu.Lock()
+		defer dedupeCalls.mu.Unlock()
+		if dedupeCalls.calls[key] == c {
+			delete(dedupeCalls.calls, key)
+		}
+	})
+
+	defer close(c.done)
+	c.httpStatusCode, c.header, c.respBody, c.err = opts.sendWithFallback(method, url)
+	if c.err != nil && opts.ctx.Err() != nil {
+		// 失败只是因为这个调用方取消或超时, 不是服务端的结果: 之后的请求不再使用它, 等待中的请求各自重新判断
+		c.canceled = true
+		dedupeCalls.mu.Lock()
+		if dedupeCalls.calls[key] == c {
+			delete(dedupeCalls.calls, key)
+		}
+		dedupeCalls.mu.Unlock()
+	}
+	return c.httpStatusCode, c.header.Clone(), bytes.Clone(c.respBody), c.err
+}
@@ -0,0 +1,103 @@
+package httptool
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestWithDedupeWindow 测试时间窗口内的重复请求去重
+func TestWithDedupeWindow(t *testing.T) {
+	ResetDefaultClient()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("created"))
+	}))
+	defer server.Close()
+
+	GetHttpClient() // 先初始化全局客户端, 本用例只关注去重
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, body, err := Post(ctx, server.URL, []byte(`{}`), WithDedupeWindow("submit-order", 200*time.Millisecond))
+			if err != nil || string(body) != "created" {
+				t.Errorf("期望返回第一个请求的结果, 得到 %q %v", string(body), err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("窗口内的重复请求只应发出 1 个, 得到 %d", n)
+	}
+
+	// 窗口过后重新发出请求
+	time.Sleep(200 * time.Millisecond)
+	if _, _, err := Post(ctx, server.URL, []byte(`{}`), WithDedupeWindow("submit-order", 200*time.Millisecond)); err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Fatalf("窗口过后应重新发出请求, 共 %d 个", n)
+	}
+}
+
+// TestDedupeLeaderCanceled 测试第一个请求的调用方取消后, 等待中的请求重新发起而不是返回别人的取消错误
+func TestDedupeLeaderCanceled(t *testing.T) {
+	ResetDefaultClient()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		select {
+		case <-time.After(100 * time.Millisecond):
+			w.Write([]byte("ok"))
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	GetHttpClient() // 先初始化全局客户端, 本用例只关注去重
+	// 每次运行使用不同的 key, 不受上一次运行的窗口影响
+	dedupe := WithDedupeWindow(server.URL, time.Second)
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, _, err := Get(leaderCtx, server.URL, dedupe)
+		leaderErr <- err
+	}()
+	for deadline := time.Now().Add(time.Second); requests.Load() == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, body, err := Get(context.Background(), server.URL, dedupe)
+			if err != nil || string(body) != "ok" {
+				t.Errorf("第一个请求被取消后等待中的请求应重新发起, 得到 %q %v", string(body), err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond) // 等后面的请求开始等待
+	cancel()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("被取消的请求期望 context.Canceled, 得到 %v", err)
+	}
+	wg.Wait()
+	if n := requests.Load(); n != 2 {
+		t.Fatalf("等待中的请求应只重新发出 1 个, 共 %d 个", n)
+	}
+}
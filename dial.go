@@ -0,0 +1,55 @@
+package httptool
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+)
+
+// DialError 建立连接失败, 附带请求的 host 和实际尝试连接过的地址, 便于排查是连哪个IP失败了
+type DialError struct {
+	Host  string   // 请求URL中的 host
+	Addrs []string // 尝试连接的地址, 包含DNS解析出的IP和端口
+	Err   error
+}
+
+func (e *DialError) Error() string {
+	return fmt.Sprintf("dial %s (tried %s): %v", e.Host, strings.Join(e.Addrs, ", "), e.Err)
+}
+
+func (e *DialError) Unwrap() error {
+	return e.Err
+}
+
+// dialAttempts 通过 httptrace 记录请求尝试连接过的地址, 双栈时可能并发拨号所以需要加锁
+type dialAttempts struct {
+	mu    sync.Mutex
+	addrs []string
+}
+
+// trace 给请求挂载记录拨号地址的钩子
+func (a *dialAttempts) trace(req *http.Request) *http.Request {
+	trace := &httptrace.ClientTrace{
+		ConnectStart: func(network, addr string) {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			a.addrs = append(a.addrs, addr)
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+// wrap 拨号失败时把错误包装成 DialError, 其他错误原样返回
+func (a *dialAttempts) wrap(req *http.Request, err error) error {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) || opErr.Op != "dial" {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return &DialError{Host: req.URL.Host, Addrs: append([]string(nil), a.addrs...), Err: err}
+}
@@ -0,0 +1,36 @@
+package httptool
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestDialError 测试连接失败时的错误包含目标地址
+func TestDialError(t *testing.T) {
+	ResetDefaultClient()
+
+	// 已关闭的服务器, 模拟连接被拒绝
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	mockLogger := &MockLogger{}
+	_, _, err := Get(context.Background(), server.URL, WithLogger(mockLogger))
+	var dialErr *DialError
+	if !errors.As(err, &dialErr) {
+		t.Fatalf("期望 DialError, 得到 %v", err)
+	}
+	if dialErr.Host != addr || len(dialErr.Addrs) == 0 || dialErr.Addrs[0] != addr {
+		t.Fatalf("DialError 内容不符合预期: %+v", dialErr)
+	}
+	if !strings.Contains(err.Error(), addr) {
+		t.Fatalf("错误信息应包含尝试的地址, 得到 %v", err)
+	}
+	if !mockLogger.errorCalled {
+		t.Fatal("连接失败应记录 Error 日志")
+	}
+}
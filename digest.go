@@ -0,0 +1,106 @@
+package httptool
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrDigestMismatch 开启 WithVerifyDigest 后响应体的摘要与 Digest 响应头不一致, 或响应头中没有支持的摘要时返回
+var ErrDigestMismatch = errors.New("response body digest mismatch")
+
+// digestAlgorithms Digest 响应头中支持的算法, 键为小写的算法名
+var digestAlgorithms = map[string]func() hash.Hash{
+	"sha-256": sha256.New,
+	"sha-512": sha512.New,
+}
+
+// WithVerifyDigest 按 Digest 响应头(如 sha-256=X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=)校验响应体, 支持 sha-256 和 sha-512
+// 响应头中有多个支持的摘要时全部都要一致; 不一致或没有支持的摘要时返回 ErrDigestMismatch, 此时不返回响应体
+// 摘要按传输的字节(解压之前)计算, Transport 自动解压的响应只能按解压后的字节计算; 只校验成功的响应
+func WithVerifyDigest() Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.verifyDigest = true
+		return
+	})
+}
+
+type expectedDigest struct {
+	algorithm string
+	sum       []byte
+	hash      hash.Hash
+}
+
+// digestVerifier 在读取响应体的同时计算摘要
+type digestVerifier struct {
+	body     io.Reader
+	expected []expectedDigest
+}
+
+// wrapDigest 开启了 WithVerifyDigest 时解析 Digest 响应头, 并在响应体上加上摘要计算, 没有开启时返回 nil
+func (opts *requestOption) wrapDigest(resp *http.Response) (*digestVerifier, error) {
+	if !opts.verifyDigest {
+		return nil, nil
+	}
+	expected, err := parseDigestHeader(resp.Header.Values("Digest"))
+	if err != nil {
+		return nil, err
+	}
+	writers := make([]io.Writer, len(expected))
+	for i := range expected {
+		writers[i] = expected[i].hash
+	}
+	d := &digestVerifier{body: io.TeeReader(resp.Body, io.MultiWriter(writers...)), expected: expected}
+	resp.Body = contentLengthBody{Reader: d.body, Closer: resp.Body}
+	return d, nil
+}
+
+// parseDigestHeader 解析 Digest 响应头, 忽略不支持的算法
+func parseDigestHeader(values []string) ([]expectedDigest, error) {
+	var expected []expectedDigest
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			algorithm, encoded, ok := strings.Cut(strings.TrimSpace(item), "=")
+			if !ok {
+				continue
+			}
+			algorithm = strings.ToLower(strings.TrimSpace(algorithm))
+			newHash, supported := digestAlgorithms[algorithm]
+			if !supported {
+				continue
+			}
+			sum, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid %s value %q", ErrDigestMismatch, algorithm, encoded)
+			}
+			expected = append(expected, expectedDigest{algorithm: algorithm, sum: sum, hash: newHash()})
+		}
+	}
+	if len(expected) == 0 {
+		return nil, fmt.Errorf("%w: no sha-256 or sha-512 digest in Digest header", ErrDigestMismatch)
+	}
+	return expected, nil
+}
+
+// verify 读完剩余的响应体(如压缩格式的结尾部分)后比较摘要, d 为 nil 时不校验
+func (d *digestVerifier) verify() error {
+	if d == nil {
+		return nil
+	}
+	if _, err := io.Copy(io.Discard, d.body); err != nil {
+		return err
+	}
+	for _, e := range d.expected {
+		if !bytes.Equal(e.hash.Sum(nil), e.sum) {
+			return fmt.Errorf("%w: %s", ErrDigestMismatch, e.algorithm)
+		}
+	}
+	return nil
+}
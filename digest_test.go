@@ -0,0 +1,82 @@
+package httptool
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// TestVerifyDigest 测试按 Digest 响应头校验响应体
+func TestVerifyDigest(t *testing.T) {
+	ResetDefaultClient()
+
+	payload := []byte(`{"amount":100}`)
+	sum256 := sha256.Sum256(payload)
+	sum512 := sha512.Sum512(payload)
+	good256 := "sha-256=" + base64.StdEncoding.EncodeToString(sum256[:])
+	good512 := "SHA-512=" + base64.StdEncoding.EncodeToString(sum512[:])
+
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	zw.Write(payload)
+	zw.Close()
+	sumGzip := sha256.Sum256(gzipped.Bytes())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gzip":
+			// 摘要按压缩后传输的字节计算
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Header().Set("Digest", "sha-256="+base64.StdEncoding.EncodeToString(sumGzip[:]))
+			w.Write(gzipped.Bytes())
+			return
+		case "/none":
+		default:
+			w.Header().Set("Digest", r.URL.Query().Get("digest"))
+		}
+		w.Write(payload)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	for _, digest := range []string{good256, good512, "md5=HUXZLQLMuI/KZ5KDcJPcOA==, " + good256 + "," + good512} {
+		_, body, err := Get(ctx, server.URL+"/?digest="+url.QueryEscape(digest), WithVerifyDigest())
+		if err != nil || !bytes.Equal(body, payload) {
+			t.Fatalf("Digest %q 应校验通过, 得到 %q %v", digest, string(body), err)
+		}
+	}
+
+	// 多个摘要中有一个不一致也算失败, 不返回响应体
+	bad := good256 + ", sha-512=" + base64.StdEncoding.EncodeToString(make([]byte, 64))
+	_, body, err := Get(ctx, server.URL+"/?digest="+url.QueryEscape(bad), WithVerifyDigest())
+	if !errors.Is(err, ErrDigestMismatch) || body != nil {
+		t.Fatalf("期望 ErrDigestMismatch 且响应体为 nil, 得到 %q %v", string(body), err)
+	}
+
+	// 没有 Digest 响应头或只有不支持的算法时无法校验
+	if _, _, err = Get(ctx, server.URL+"/none", WithVerifyDigest()); !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("缺少 Digest 响应头时期望 ErrDigestMismatch, 得到 %v", err)
+	}
+	if _, _, err = Get(ctx, server.URL+"/?digest=md5%3DHUXZLQLMuI%2FKZ5KDcJPcOA%3D%3D", WithVerifyDigest()); !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("只有不支持的算法时期望 ErrDigestMismatch, 得到 %v", err)
+	}
+
+	// 不开启时不校验
+	if _, _, err = Get(ctx, server.URL+"/?digest="+url.QueryEscape(bad)); err != nil {
+		t.Fatalf("未开启 WithVerifyDigest 时不应校验, 得到 %v", err)
+	}
+
+	// 显式设置 Accept-Encoding, 由 httptool 解压, 按压缩后的字节校验
+	_, body, err = Get(ctx, server.URL+"/gzip", WithVerifyDigest(), WithHeaders(map[string]string{"Accept-Encoding": "gzip"}))
+	if err != nil || !bytes.Equal(body, payload) {
+		t.Fatalf("压缩的响应应按传输的字节校验通过, 得到 %q %v", string(body), err)
+	}
+}
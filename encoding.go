@@ -0,0 +1,35 @@
+package httptool
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrUnsupportedContentEncoding 响应的 Content-Encoding 无法解码时返回, 避免把压缩后的字节当作响应体返回
+var ErrUnsupportedContentEncoding = errors.New("unsupported response Content-Encoding")
+
+// decodeBody 按响应的 Content-Encoding 解压响应体
+// Go 的 Transport 自己添加 Accept-Encoding 时会透明解压 gzip 并删除该响应头, 这里处理调用方自行设置 Accept-Encoding 的情况
+func decodeBody(resp *http.Response) (io.Reader, error) {
+	if resp.ContentLength == 0 || (resp.Request != nil && resp.Request.Method == http.MethodHead) {
+		return resp.Body, nil // 没有响应体
+	}
+	switch encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(resp.Body)
+	case "deflate":
+		return zlib.NewReader(resp.Body)
+	case "br":
+		// 标准库没有 brotli 解码器, 为了不引入第三方依赖这里不支持, 调用方不应在 Accept-Encoding 中声明 br
+		return nil, fmt.Errorf("%w: br (brotli is not supported, remove it from Accept-Encoding)", ErrUnsupportedContentEncoding)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentEncoding, encoding)
+	}
+}
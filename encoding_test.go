@@ -0,0 +1,61 @@
+package httptool
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestDecodeBody 测试按 Content-Encoding 解压响应体
+func TestDecodeBody(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := r.URL.Query().Get("encoding")
+		w.Header().Set("Content-Encoding", encoding)
+		w.WriteHeader(http.StatusOK)
+		var zw io.WriteCloser
+		switch encoding {
+		case "gzip":
+			zw = gzip.NewWriter(w)
+		case "deflate":
+			zw = zlib.NewWriter(w)
+		default:
+			w.Write([]byte("compressed bytes"))
+			return
+		}
+		zw.Write([]byte(`{"status":"ok"}`))
+		zw.Close()
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	acceptEncoding := WithHeaders(map[string]string{"Accept-Encoding": "gzip, deflate, br"})
+
+	for _, encoding := range []string{"gzip", "deflate"} {
+		t.Run(encoding, func(t *testing.T) {
+			_, body, err := Get(ctx, server.URL+"?encoding="+encoding, acceptEncoding)
+			if err != nil {
+				t.Fatalf("请求失败: %v", err)
+			}
+			if string(body) != `{"status":"ok"}` {
+				t.Fatalf("期望解压后的响应体 %s, 得到 %s", `{"status":"ok"}`, string(body))
+			}
+		})
+	}
+
+	t.Run("br", func(t *testing.T) {
+		_, body, err := Get(ctx, server.URL+"?encoding=br", acceptEncoding)
+		if !errors.Is(err, ErrUnsupportedContentEncoding) {
+			t.Fatalf("期望错误 %v, 得到 %v", ErrUnsupportedContentEncoding, err)
+		}
+		if body != nil {
+			t.Fatalf("不应返回未解码的响应体, 得到 %s", string(body))
+		}
+	})
+}
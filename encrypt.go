@@ -0,0 +1,61 @@
+package httptool
+
+import (
+	"fmt"
+	"io"
+)
+
+// WithEncryptBody 发送前用 encrypt 加密请求体, 用于 TLS 之外还要求应用层加密的接口(如 JWE)
+// 加密在所有其他处理之后进行: WithValidateJSON 校验、WithIdempotencyKey 计算幂等键用的都是明文, 调用方自行压缩的请求体先压缩再加密;
+// 每个请求只加密一次, 重试、备用地址发送的是同一份密文; WithFileBody、WithBodyReader 的请求体会先整个读进内存
+func WithEncryptBody(encrypt func(plaintext []byte) ([]byte, error)) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.encryptBody = encrypt
+		return
+	})
+}
+
+// WithDecryptBody 读取响应体后用 decrypt 解密, 在 Content-Encoding 解压之后、WithBodyReencode 和各种响应校验之前进行
+// 只解密 2xx(或 WithExpectedStatus 指定的)响应, 网关返回的错误响应通常没有加密; 响应体为空时不调用
+func WithDecryptBody(decrypt func(ciphertext []byte) ([]byte, error)) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.decryptBody = decrypt
+		return
+	})
+}
+
+// encryptRequestBody 设置了 WithEncryptBody 时把请求体替换为密文
+func (opts *requestOption) encryptRequestBody() error {
+	if opts.encryptBody == nil {
+		return nil
+	}
+	plaintext := opts.data
+	if opts.bodyFunc != nil {
+		body, _, err := opts.bodyFunc()
+		if err != nil {
+			return err
+		}
+		if plaintext, err = io.ReadAll(body); err != nil {
+			return err
+		}
+		opts.bodyFunc, opts.bodyNotReplayable = nil, false
+	}
+	ciphertext, err := opts.encryptBody(plaintext)
+	if err != nil {
+		return fmt.Errorf("encrypt request body: %w", err)
+	}
+	opts.data = ciphertext
+	return nil
+}
+
+// decryptResponseBody 设置了 WithDecryptBody 时解密响应体
+func (opts *requestOption) decryptResponseBody(respBody []byte) ([]byte, error) {
+	if opts.decryptBody == nil || len(respBody) == 0 {
+		return respBody, nil
+	}
+	plaintext, err := opts.decryptBody(respBody)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt response body: %w", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,64 @@
+package httptool
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestEncryptBody 测试请求体加密和响应体解密
+func TestEncryptBody(t *testing.T) {
+	ResetDefaultClient()
+
+	block, _ := aes.NewCipher(bytes.Repeat([]byte{1}, 32))
+	gcm, _ := cipher.NewGCM(block)
+	seal := func(plaintext []byte) ([]byte, error) {
+		nonce := make([]byte, gcm.NonceSize())
+		rand.Read(nonce)
+		return gcm.Seal(nonce, nonce, plaintext, nil), nil
+	}
+	open := func(ciphertext []byte) ([]byte, error) {
+		if len(ciphertext) < gcm.NonceSize() {
+			return nil, errors.New("ciphertext too short")
+		}
+		return gcm.Open(nil, ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():], nil)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ciphertext, _ := io.ReadAll(r.Body)
+		plaintext, err := open(ciphertext)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("bad ciphertext"))
+			return
+		}
+		reply, _ := seal(append([]byte("echo "), plaintext...))
+		w.Write(reply)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	_, body, err := Post(ctx, server.URL, []byte(`{"card":"4111"}`), WithEncryptBody(seal), WithDecryptBody(open), WithValidateJSON())
+	if err != nil || string(body) != `echo {"card":"4111"}` {
+		t.Fatalf("期望解密后的响应体, 得到 %q %v", string(body), err)
+	}
+
+	// 非 2xx 的错误响应不解密
+	status, body, err := Post(ctx, server.URL, []byte("plain"), WithDecryptBody(open))
+	if status != http.StatusBadRequest || string(body) != "bad ciphertext" || err == nil {
+		t.Fatalf("期望原样返回错误响应, 得到 %d %q %v", status, string(body), err)
+	}
+
+	errEncrypt := errors.New("no key")
+	_, _, err = Post(ctx, server.URL, []byte("{}"), WithEncryptBody(func([]byte) ([]byte, error) { return nil, errEncrypt }))
+	if !errors.Is(err, errEncrypt) {
+		t.Fatalf("期望加密失败的错误, 得到 %v", err)
+	}
+}
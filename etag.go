@@ -0,0 +1,83 @@
+package httptool
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+)
+
+// ETagStore 保存 URL 对应的 ETag 和响应体, 用于 WithETagStore 的条件请求
+type ETagStore interface {
+	Get(url string) (etag string, body []byte, ok bool)
+	Set(url string, etag string, body []byte)
+}
+
+// NewMemoryETagStore 创建并发安全的内存 ETagStore
+func NewMemoryETagStore() ETagStore {
+	return &memoryETagStore{entries: map[string]etagEntry{}}
+}
+
+type etagEntry struct {
+	etag string
+	body []byte
+}
+
+type memoryETagStore struct {
+	mu      sync.RWMutex
+	entries map[string]etagEntry
+}
+
+func (s *memoryETagStore) Get(url string) (etag string, body []byte, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.entries[url]
+	return entry.etag, entry.body, ok
+}
+
+func (s *memoryETagStore) Set(url string, etag string, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[url] = etagEntry{etag: etag, body: body}
+}
+
+// WithETagStore 使用 store 自动做 ETag 条件请求, 只对 GET 请求生效
+// 发送前根据 store 中的 ETag 设置 If-None-Match, 返回 200 时用响应的 ETag 更新 store,
+// 返回 304 时不报错, 状态码为 304, 响应体为 store 中保存的响应体的拷贝
+// 设置了 WithBodyReadLimit、WithCompressToFile 或流式处理响应体的请求不使用 store
+func WithETagStore(store ETagStore) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.etagStore, err = store, nil
+		return
+	})
+}
+
+// etagCacheable 请求是否使用 ETagStore: 只有 GET 请求, 且响应体完整地读入内存时才能缓存
+// WithBodyReadLimit 可能截断响应体, WithCompressToFile 和流式处理不返回响应体, 这些请求既不保存也不做条件请求
+func (opts *requestOption) etagCacheable(req *http.Request) bool {
+	return opts.etagStore != nil && req.Method == http.MethodGet &&
+		opts.bodyReadLimit == 0 && opts.compressToFile == "" && opts.bodyConsumer == nil
+}
+
+// setIfNoneMatch 根据 store 设置 If-None-Match, 返回 store 中保存的响应体的拷贝
+func (opts *requestOption) setIfNoneMatch(req *http.Request, url string) (cachedBody []byte, ok bool) {
+	if !opts.etagCacheable(req) || req.Header.Get("If-None-Match") != "" {
+		return nil, false
+	}
+	etag, body, ok := opts.etagStore.Get(url)
+	if !ok || etag == "" {
+		return nil, false
+	}
+	req.Header.Set("If-None-Match", etag)
+	return bytes.Clone(body), true // 调用方修改返回的响应体不影响 store 中保存的
+}
+
+// storeETag 用成功响应的 ETag 更新 store
+func (opts *requestOption) storeETag(req *http.Request, url string, header http.Header, respBody []byte) {
+	if !opts.etagCacheable(req) {
+		return
+	}
+	if etag := header.Get("ETag"); etag != "" {
+		// 保存一份拷贝: 调用方可能修改返回的响应体, 缓冲池中的响应体也会被归还复用
+		opts.etagStore.Set(url, etag, bytes.Clone(respBody))
+	}
+}
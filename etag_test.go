@@ -0,0 +1,85 @@
+package httptool
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestWithETagStore 测试 ETag 条件请求
+func TestWithETagStore(t *testing.T) {
+	ResetDefaultClient()
+
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		hits++
+		w.Header().Set("ETag", `"v1"`)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"version":1}`))
+	}))
+	defer server.Close()
+
+	store := NewMemoryETagStore()
+	ctx := context.Background()
+
+	statusCode, body, err := Get(ctx, server.URL, WithETagStore(store))
+	if err != nil || statusCode != http.StatusOK || string(body) != `{"version":1}` {
+		t.Fatalf("首次请求失败: %d %s %v", statusCode, string(body), err)
+	}
+	if etag, _, ok := store.Get(server.URL); !ok || etag != `"v1"` {
+		t.Fatalf("应保存响应的 ETag, 得到 %q", etag)
+	}
+
+	statusCode, body, err = Get(ctx, server.URL, WithETagStore(store))
+	if err != nil {
+		t.Fatalf("304 不应返回错误: %v", err)
+	}
+	if statusCode != http.StatusNotModified || string(body) != `{"version":1}` {
+		t.Fatalf("期望 304 和保存的响应体, 得到 %d %s", statusCode, string(body))
+	}
+	if hits != 1 {
+		t.Fatalf("第二次请求应命中条件请求, 完整响应次数 %d", hits)
+	}
+
+	// 修改返回的响应体不影响 store 中保存的
+	body[0] = 'X'
+	_, body, _ = Get(ctx, server.URL, WithETagStore(store))
+	if string(body) != `{"version":1}` {
+		t.Fatalf("修改返回的响应体不应影响 store, 得到 %s", string(body))
+	}
+}
+
+// TestETagStoreTruncatedBody 测试截断的响应体不保存到 ETagStore
+func TestETagStoreTruncatedBody(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("0123456789"))
+	}))
+	defer server.Close()
+
+	store := NewMemoryETagStore()
+	ctx := context.Background()
+	_, body, err := Get(ctx, server.URL, WithETagStore(store), WithBodyReadLimit(3))
+	if err != nil || string(body) != "012" {
+		t.Fatalf("期望截断的响应体, 得到 %q %v", string(body), err)
+	}
+	if _, _, ok := store.Get(server.URL); ok {
+		t.Fatal("截断的响应体不应保存到 store")
+	}
+
+	statusCode, body, err := Get(ctx, server.URL, WithETagStore(store))
+	if err != nil || statusCode != http.StatusOK || string(body) != "0123456789" {
+		t.Fatalf("之后的完整请求应拿到完整的响应体, 得到 %d %q %v", statusCode, string(body), err)
+	}
+}
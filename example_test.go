@@ -4,6 +4,7 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"time"
 )
@@ -65,6 +66,24 @@ func ExamplePost() {
 	statusCode, body, err = Post(ctx, "https://api.example.com/users", data, options...)
 }
 
+// ExamplePostForm 展示表单POST请求的使用方法
+func ExamplePostForm() {
+	ctx := context.Background()
+
+	// 准备表单数据
+	values := url.Values{}
+	values.Set("name", "张三")
+	values.Set("age", "25")
+
+	statusCode, body, err := PostForm(ctx, "https://api.example.com/login", values, WithTimeout(10*time.Second))
+	if err != nil {
+		// 处理错误
+		return
+	}
+	_ = statusCode
+	_ = body
+}
+
 // ExampleRequest 展示通用Request方法的使用
 func ExampleRequest() {
 	ctx := context.Background()
@@ -94,8 +113,8 @@ func ExampleRequest() {
 	statusCode, body, err = Request("DELETE", "https://api.example.com/users/1", WithContext(ctx))
 }
 
-// ExampleCustomClient 展示自定义HTTP客户端的使用
-func ExampleCustomClient() {
+// ExampleSetHttpClient 展示自定义HTTP客户端的使用
+func ExampleSetHttpClient() {
 	// 创建自定义的HTTP客户端
 	customClient := &http.Client{
 		Timeout: 30 * time.Second,
@@ -0,0 +1,61 @@
+package httptool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// GraphQLErrorDetail GraphQL 响应 errors 数组中的一项
+type GraphQLErrorDetail struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// GraphQLError GraphQL 响应中的 errors 数组, 服务端返回了部分 data 时 data 仍会解析到 resp 中
+type GraphQLError struct {
+	Errors []GraphQLErrorDetail
+}
+
+func (e *GraphQLError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, detail := range e.Errors {
+		messages[i] = detail.Message
+	}
+	return "graphql: " + strings.Join(messages, "; ")
+}
+
+// GraphQL 发起 GraphQL 请求: 把 query 和 variables 封装成标准的 {"query":..., "variables":...} JSON 发送, 把响应的 data 解析到 resp
+// 响应包含 errors 时返回 *GraphQLError, 如果同时有 data(部分成功), data 依然会解析到 resp; resp 为nil时不解析 data
+func GraphQL(ctx context.Context, url string, query string, variables map[string]any, resp any, options ...Option) error {
+	reqBody, err := json.Marshal(struct {
+		Query     string         `json:"query"`
+		Variables map[string]any `json:"variables,omitempty"`
+	}{query, variables})
+	if err != nil {
+		return fmt.Errorf("encode graphql request: %w", err)
+	}
+	_, respBody, err := Post(ctx, url, reqBody, options...)
+	if err != nil {
+		return err
+	}
+
+	var envelope struct {
+		Data   json.RawMessage      `json:"data"`
+		Errors []GraphQLErrorDetail `json:"errors"`
+	}
+	if err := json.Unmarshal(respBody, &envelope); err != nil {
+		return fmt.Errorf("decode graphql response: %w", err)
+	}
+	if resp != nil && len(envelope.Data) > 0 && string(envelope.Data) != "null" {
+		if err := json.Unmarshal(envelope.Data, resp); err != nil {
+			return fmt.Errorf("decode graphql data: %w", err)
+		}
+	}
+	if len(envelope.Errors) > 0 {
+		return &GraphQLError{Errors: envelope.Errors}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package httptool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestGraphQL 测试 GraphQL 请求的封装和响应解析
+func TestGraphQL(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		if req.Variables["id"] == "missing" {
+			// 部分成功: 同时返回 data 和 errors
+			w.Write([]byte(`{"data":{"user":{"name":"partial"}},"errors":[{"message":"friends not found","path":["user","friends"]}]}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"user":{"name":"alice"}}}`))
+	}))
+	defer server.Close()
+
+	type result struct {
+		User struct {
+			Name string `json:"name"`
+		} `json:"user"`
+	}
+	query := `query($id: ID!) { user(id: $id) { name friends { name } } }`
+
+	var ok result
+	if err := GraphQL(context.Background(), server.URL, query, map[string]any{"id": "1"}, &ok); err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if ok.User.Name != "alice" {
+		t.Fatalf("期望解析出 alice, 得到 %q", ok.User.Name)
+	}
+
+	var partial result
+	err := GraphQL(context.Background(), server.URL, query, map[string]any{"id": "missing"}, &partial)
+	var gqlErr *GraphQLError
+	if !errors.As(err, &gqlErr) || len(gqlErr.Errors) != 1 || gqlErr.Errors[0].Message != "friends not found" {
+		t.Fatalf("期望 GraphQLError, 得到 %v", err)
+	}
+	if partial.User.Name != "partial" {
+		t.Fatalf("部分成功时仍应解析 data, 得到 %q", partial.User.Name)
+	}
+}
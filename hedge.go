@@ -0,0 +1,111 @@
+package httptool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WithHedging 对冲请求: 请求发出 after 之后还没有完成时, 再并行发出一个相同的请求, 最多额外发出 maxHedges 个
+// 使用最先成功完成的结果并取消其他请求, 以增加服务端负载为代价降低长尾延迟
+// 只允许幂等的方法(GET、HEAD、OPTIONS、PUT、DELETE); 设置了 WithStats 时 Stats.HedgeAttempt 记录胜出的是第几个请求
+// 每个请求都会读取响应体, 不能与把响应体交给外部的 WithCompressToFile、WithBodyObserver 一起使用;
+// 各请求同时发送请求体, WithBodyReader 传入的 Reader 需要实现 io.ReaderAt
+func WithHedging(after time.Duration, maxHedges int) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		if after <= 0 || maxHedges <= 0 {
+			return fmt.Errorf("hedging requires positive delay and max hedges, got %s and %d", after, maxHedges)
+		}
+		opts.hedgeAfter, opts.maxHedges = after, maxHedges
+		return
+	})
+}
+
+// isIdempotent 方法是否幂等, 幂等的请求才能安全地重复发送
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// errHedgeLost 对冲请求中落败的请求被取消的原因
+var errHedgeLost = errors.New("httptool: hedged request lost the race")
+
+type hedgeResult struct {
+	attempt        int
+	httpStatusCode int
+	header         http.Header
+	respBody       []byte
+	err            error
+	stats          *Stats
+}
+
+// sendHedged 设置了 WithHedging 时并行发出对冲请求, 否则直接发送
+func (opts *requestOption) sendHedged(method string, url string) (httpStatusCode int, header http.Header, respBody []byte, err error) {
+	if opts.hedgeAfter <= 0 {
+		return opts.send(method, url)
+	}
+	if !isIdempotent(method) {
+		err = fmt.Errorf("hedging is only allowed for idempotent methods, got %s", method)
+		return
+	}
+	// 这些选项在读取响应体的同时产生副作用, 多个请求同时读取会写同一个文件或重复回调
+	if opts.compressToFile != "" || opts.bodyObserver != nil || opts.bodyConsumer != nil {
+		err = errors.New("hedging cannot be combined with WithCompressToFile, WithBodyObserver or streamed bodies")
+		return
+	}
+	// 多个请求同时 Seek 和读取同一个 Reader 会互相破坏请求体
+	if opts.bodySharedSeeker {
+		err = errors.New("hedging requires a request body that can be read concurrently, such as WithData or an io.ReaderAt")
+		return
+	}
+
+	// 选出结果后取消其他还在进行的请求; results 有缓冲, 被取消的请求结束后不会阻塞
+	ctx, cancel := context.WithCancelCause(opts.ctx)
+	defer cancel(errHedgeLost)
+	results := make(chan hedgeResult, opts.maxHedges+1)
+	launched, pending := 0, 0
+	launch := func() {
+		attempt := *opts // 每个请求使用独立的上下文和统计信息, 互不干扰
+		attempt.ctx = ctx
+		if opts.stats != nil {
+			stats := *opts.stats
+			attempt.stats = &stats
+		}
+		r := hedgeResult{attempt: launched, stats: attempt.stats}
+		launched++
+		pending++
+		go func() {
+			r.httpStatusCode, r.header, r.respBody, r.err = attempt.send(method, url)
+			results <- r
+		}()
+	}
+
+	launch()
+	timer := time.NewTimer(opts.hedgeAfter)
+	defer timer.Stop()
+	for {
+		select {
+		case r := <-results:
+			pending--
+			// 失败时如果还有请求在进行就等待它们, 对冲只针对慢请求, 不对失败的请求重试
+			if shouldFallback(r.httpStatusCode, r.err) && pending > 0 {
+				continue
+			}
+			if opts.stats != nil {
+				*opts.stats = *r.stats
+				opts.stats.HedgeAttempt = r.attempt
+			}
+			return r.httpStatusCode, r.header, r.respBody, r.err
+		case <-timer.C:
+			if launched <= opts.maxHedges {
+				launch()
+				timer.Reset(opts.hedgeAfter)
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package httptool
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestWithHedging 测试对冲请求
+func TestWithHedging(t *testing.T) {
+	ResetDefaultClient()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			// 第一个请求很慢, 被取消后提前返回
+			select {
+			case <-time.After(2 * time.Second):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	GetHttpClient() // 先初始化全局客户端, 本用例只关注对冲请求
+	ctx := context.Background()
+	t.Run("对冲请求胜出", func(t *testing.T) {
+		var stats Stats
+		start := time.Now()
+		_, body, err := Get(ctx, server.URL, WithHedging(50*time.Millisecond, 2), WithStats(&stats))
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		if string(body) != "ok" || stats.HedgeAttempt != 1 {
+			t.Fatalf("期望第 1 个对冲请求胜出, 得到 %q 第 %d 个", string(body), stats.HedgeAttempt)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("对冲后不应等待慢请求, 耗时 %s", elapsed)
+		}
+	})
+
+	t.Run("不能写文件", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "body.gz")
+		if _, _, err := Get(ctx, server.URL, WithHedging(50*time.Millisecond, 1), WithCompressToFile(path)); err == nil {
+			t.Fatal("对冲请求与 WithCompressToFile 一起使用时期望返回错误")
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("不应创建文件, 得到 %v", err)
+		}
+	})
+
+	t.Run("不能观察响应体", func(t *testing.T) {
+		var observed atomic.Int32
+		observer := func(chunk []byte) { observed.Add(int32(len(chunk))) }
+		if _, _, err := Get(ctx, server.URL, WithHedging(50*time.Millisecond, 1), WithBodyObserver(observer)); err == nil {
+			t.Fatal("对冲请求与 WithBodyObserver 一起使用时期望返回错误")
+		}
+		if observed.Load() != 0 {
+			t.Fatalf("不应回调 observer, 得到 %d 字节", observed.Load())
+		}
+	})
+
+	t.Run("请求体不能并发读取", func(t *testing.T) {
+		// 只实现了 Read 和 Seek, 各请求共用同一个读取位置
+		body := struct{ io.ReadSeeker }{bytes.NewReader([]byte("payload"))}
+		before := requests.Load()
+		if _, _, err := Put(ctx, server.URL, nil, WithHedging(50*time.Millisecond, 1), WithBodyReader(body)); err == nil {
+			t.Fatal("对冲请求的请求体不支持 ReadAt 时期望返回错误")
+		}
+		if requests.Load() != before {
+			t.Fatalf("不应发出请求, 得到 %d 个", requests.Load()-before)
+		}
+		if _, _, err := Put(ctx, server.URL, nil, WithHedging(50*time.Millisecond, 1), WithBodyReader(bytes.NewReader([]byte("payload")))); err != nil {
+			t.Fatalf("请求体支持 ReadAt 时可以对冲, 得到 %v", err)
+		}
+	})
+
+	t.Run("非幂等方法", func(t *testing.T) {
+		if _, _, err := Post(ctx, server.URL, nil, WithHedging(50*time.Millisecond, 1)); err == nil {
+			t.Fatal("POST 请求设置对冲时期望返回错误")
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package httptool
+
+import (
+	"net/url"
+	"sync"
+	"time"
+)
+
+var (
+	hostTimeoutsMu sync.RWMutex
+	hostTimeouts   = map[string]time.Duration{}
+)
+
+// RegisterHostTimeout 为指定 host 注册默认超时时间, 请求这个 host 且没有通过 WithTimeout 设置超时、上下文也没有 deadline 时使用它
+// host 可以带端口(如 "api.example.com:8443"), 带端口的注册优先于只有主机名的; timeout <= 0 时取消注册
+func RegisterHostTimeout(host string, timeout time.Duration) {
+	hostTimeoutsMu.Lock()
+	defer hostTimeoutsMu.Unlock()
+	if timeout <= 0 {
+		delete(hostTimeouts, host)
+		return
+	}
+	hostTimeouts[host] = timeout
+}
+
+// applyHostTimeout 没有显式设置超时时使用请求地址的 host 注册的超时
+func (opts *requestOption) applyHostTimeout(rawURL string) {
+	if opts.timeoutSet {
+		return
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return // 地址不合法时由创建请求时报错
+	}
+	hostTimeoutsMu.RLock()
+	defer hostTimeoutsMu.RUnlock()
+	if timeout, ok := hostTimeouts[u.Host]; ok {
+		opts.timeout = timeout
+	} else if timeout, ok := hostTimeouts[u.Hostname()]; ok {
+		opts.timeout = timeout
+	}
+}
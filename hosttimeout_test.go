@@ -0,0 +1,38 @@
+package httptool
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRegisterHostTimeout 测试按 host 注册的默认超时
+func TestRegisterHostTimeout(t *testing.T) {
+	RegisterHostTimeout("slow.example.com", 30*time.Second)
+	RegisterHostTimeout("slow.example.com:8443", time.Minute)
+	defer RegisterHostTimeout("slow.example.com", 0)
+	defer RegisterHostTimeout("slow.example.com:8443", 0)
+
+	tests := []struct {
+		name    string
+		url     string
+		options []Option
+		want    time.Duration
+	}{
+		{"按主机名匹配", "http://slow.example.com/api", nil, 30 * time.Second},
+		{"带端口的优先", "https://slow.example.com:8443/api", nil, time.Minute},
+		{"未注册的 host 使用默认超时", "http://fast.example.com/api", nil, 5 * time.Second},
+		{"WithTimeout 优先", "http://slow.example.com/api", []Option{WithTimeout(time.Second)}, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultRequestOptions()
+			for _, opt := range tt.options {
+				opt.apply(opts)
+			}
+			opts.applyHostTimeout(tt.url)
+			if opts.timeout != tt.want {
+				t.Fatalf("期望超时 %v, 得到 %v", tt.want, opts.timeout)
+			}
+		})
+	}
+}
@@ -1,54 +1,130 @@
 package httptool
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"mime"
 	"net"
 	"net/http"
+	"net/url"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 var (
-	client *http.Client
-	once   sync.Once
+	clientMu sync.RWMutex // 保护 client, SetHttpClient 与 GetHttpClient 可以并发调用
+	client   *http.Client
 )
 
-// GetHttpClient 获取全局HTTP客户端
+// PanicError 发起请求或处理响应的过程中发生了 panic, 通常来自自定义的 RoundTripper
+type PanicError struct {
+	Value interface{} // recover 得到的值
+	Stack []byte      // panic 时的调用栈
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic during request: %v", e.Value)
+}
+
+// HTTPStatusError 响应的状态码不表示成功(默认为非 2xx, 可通过 WithExpectedStatus 修改)时返回
+// 可以用 errors.As 取出状态码和响应体, 不需要解析错误消息
+type HTTPStatusError struct {
+	StatusCode int
+	Body       []byte // 响应体, 读取失败时为 nil
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("non 200 response, response code: %d", e.StatusCode)
+}
+
+// ErrInvalidJSONBody 开启 WithValidateJSON 后请求体不是合法JSON时返回
+var ErrInvalidJSONBody = errors.New("request body is not valid JSON")
+
+// GetHttpClient 获取全局HTTP客户端, 未设置时创建默认客户端
 func GetHttpClient() *http.Client {
-	if client != nil {
-		// 因为需要支持自定义client, 所以虽然用了once.Do但是还是先判断一下client有没有实例化
-		return client
-	}
-	once.Do(func() {
-		tr := &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			MaxIdleConns:          100,
-			IdleConnTimeout:       90 * time.Second,
-			MaxIdleConnsPerHost:   50,
-			MaxConnsPerHost:       50,
-			ForceAttemptHTTP2:     true,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-		}
-		client = &http.Client{Transport: tr}
-	})
+	clientMu.RLock()
+	c := client
+	clientMu.RUnlock()
+	if c != nil {
+		return c
+	}
+	clientMu.Lock()
+	defer clientMu.Unlock()
+	// 拿到写锁后再判断一次, 其他 goroutine 可能已经创建或设置了客户端
+	if client == nil {
+		client = newDefaultClient()
+	}
 	return client
 }
 
-// SetHttpClient 提供传入自定义HttpClient方法
+func newDefaultClient() *http.Client {
+	tr := &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConnsPerHost:   50,
+		MaxConnsPerHost:       50,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	enableWarmDial(tr)
+	return &http.Client{Transport: tr}
+}
+
+// SetHttpClient 提供传入自定义HttpClient方法, 可以与正在进行的请求并发调用
+// 原客户端的 Transport 不再使用时, 从它派生的 Transport 会被移除并关闭空闲连接, 它预先建立的连接也会被关闭;
+// 原客户端之后可以再设置回来, 包括用 Preconnect 预连接
 func SetHttpClient(c *http.Client) {
+	clientMu.Lock()
+	old := client
 	client = c
+	clientMu.Unlock()
+	if old == nil {
+		return
+	}
+	if oldTr, ok := old.Transport.(*http.Transport); ok && (c == nil || c.Transport != old.Transport) {
+		derivedTransports.removeBase(oldTr)
+		closeWarmConns(oldTr)
+	}
+}
+
+// ResetDefaultClient 把全局客户端恢复到未初始化的状态, 下次调用 GetHttpClient 时重新创建默认客户端
+// 主要用于测试之间的隔离
+func ResetDefaultClient() {
+	clientMu.Lock()
+	old := client
+	client = nil
+	clientMu.Unlock()
+	derivedTransports.removeBase(nil) // 派生 Transport 与原客户端绑定, 一起清掉并关闭空闲连接
+	if old != nil {
+		if oldTr, ok := old.Transport.(*http.Transport); ok {
+			closeWarmConns(oldTr)
+		}
+	}
 }
 
 func Request(method string, url string, options ...Option) (httpStatusCode int, respBody []byte, err error) {
-	start := time.Now()
+	httpStatusCode, _, respBody, err = RequestWithResponse(method, url, options...)
+	return
+}
+
+// RequestWithResponse 发起请求, 相比 Request 额外返回响应头, 用于读取 Location、ETag、限流信息等响应头
+// 返回的响应头归调用方所有, 可以随意修改, 不会影响其他请求(包括 WithDedupeWindow 合并的请求)和 ResponseFromContext 记录的响应头
+// 没有拿到响应时 header 为 nil; 非 2xx 响应返回错误的同时也会返回响应头和响应体
+func RequestWithResponse(method string, url string, options ...Option) (httpStatusCode int, header http.Header, respBody []byte, err error) {
 	reqOpts := defaultRequestOptions() // 默认的请求选项
 	for _, opt := range options {      // 在reqOpts上应用通过options设置的选项
 		err = opt.apply(reqOpts)
@@ -56,75 +132,431 @@ func Request(method string, url string, options ...Option) (httpStatusCode int,
 			return
 		}
 	}
+	defer func() {
+		if err != nil && reqOpts.stackOnError {
+			reqOpts.logErrorStack(method, url, err)
+		}
+	}()
+	reqOpts.applyHostTimeout(url)
+	reqOpts.checkPhaseTimeouts(reqOpts.ctx)
+	reqOpts.extendLongPollTimeout()
+	reqOpts.applyAuthorization()
+	if err = reqOpts.checkRequest(); err != nil {
+		return
+	}
+	if err = reqOpts.setIdempotencyKey(); err != nil {
+		return
+	}
+	if err = reqOpts.encryptRequestBody(); err != nil {
+		return
+	}
+	reqOpts.contextWithTenant()
+
+	// 给 Request 设置Timeout, 主地址和备用地址共用这一个超时
+	// 响应体在返回之前已经读完, 返回时 cancel 可以立即释放定时器, 同时结束还在进行的对冲请求
+	var cancel context.CancelFunc
+	reqOpts.ctx, cancel = reqOpts.timeoutContext()
+	defer cancel()
+	if reqOpts.dedupeKey != "" {
+		return reqOpts.sendDeduped(method, url)
+	}
+	return reqOpts.sendWithFallback(method, url)
+}
+
+// timeoutContext 按优先级确定请求的超时:
+// 显式设置的 WithTimeout(或 WithPhaseTimeouts 的 Total) 总是生效, 与上下文的 deadline 取较早的;
+// 没有显式设置时, 上下文已有 deadline 就只用上下文的 deadline, 否则使用 RegisterHostTimeout 注册的超时或默认的 5 秒
+func (opts *requestOption) timeoutContext() (context.Context, context.CancelFunc) {
+	if _, ok := opts.ctx.Deadline(); ok && !opts.timeoutSet {
+		return context.WithCancel(opts.ctx)
+	}
+	return context.WithTimeout(opts.ctx, opts.timeout)
+}
+
+// sendWithFallback 依次尝试主地址和备用地址
+func (opts *requestOption) sendWithFallback(method string, url string) (httpStatusCode int, header http.Header, respBody []byte, err error) {
+	urls := append([]string{url}, opts.fallbackURLs...)
+	for i, u := range urls {
+		httpStatusCode, header, respBody, err = opts.sendWithRetry(method, u)
+		if !shouldFallback(httpStatusCode, err) {
+			if i > 0 && err == nil {
+				opts.logger.Info(opts.ctx, "HTTP_REQUEST_FALLBACK_LOG", opts.withLoggerFields("method", method, "url", url, "succeeded_url", u)...)
+			}
+			return
+		}
+		// 超时或取消后不再尝试备用地址; 响应体已经交给流式处理或观察者时, 换一个地址会重复交出数据
+		if opts.ctx.Err() != nil || opts.bodyHandedOut {
+			return
+		}
+	}
+	return
+}
 
+// send 向指定地址发起一次请求
+func (opts *requestOption) send(method string, url string) (httpStatusCode int, header http.Header, respBody []byte, err error) {
+	start := time.Now()
+	defer func() {
+		// 自定义的 RoundTripper 或中间件 panic 时不让它搞挂调用方的 goroutine, 转换成错误返回
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	if opts.balancer != nil {
+		var host string
+		url, host, err = opts.balancer.rewrite(url)
+		if err != nil {
+			return
+		}
+		defer func() {
+			// 请求被取消(如调用方取消或对冲请求中落败)不算 host 失败
+			opts.balancer.report(host, shouldFallback(httpStatusCode, err) && !errors.Is(opts.ctx.Err(), context.Canceled))
+		}()
+	}
 	// 创建请求对象
-	req, err := http.NewRequest(method, url, bytes.NewReader(reqOpts.data))
+	req, err := opts.newRequest(method, url)
 	if err != nil {
 		return
 	}
-	reqOpts.ctx, _ = context.WithTimeout(reqOpts.ctx, reqOpts.timeout) // 给 Request 设置Timeout
-	req = req.WithContext(reqOpts.ctx)
+	req = req.WithContext(opts.ctx)
+	opts.setTrailer(req)
 	defer req.Body.Close()
 
-	if len(reqOpts.headers) != 0 { // 设置请求头
-		for key, value := range reqOpts.headers {
-			req.Header.Add(key, value)
-		}
-	}
+	opts.setHeaders(req)
+	opts.setDeadlineHeaders(req)
+	cachedBody, revalidating := opts.setIfNoneMatch(req, url)
+	req = opts.traceRequest(req)
+	req = opts.traceConnReuse(req)
+	req = opts.traceRotation(req)
+	var attempts dialAttempts
+	req = attempts.trace(req)
 	// 发起请求
-	client := GetHttpClient()
+	client, err := opts.httpClient()
+	if err != nil {
+		return
+	}
+	client = opts.cassetteClient(client)
+	release, err := acquireInFlight(opts.ctx)
+	if err != nil {
+		opts.logCanceled(method, url, 0, err, time.Since(start))
+		return
+	}
+	defer release()
+	releaseAdaptive, err := opts.acquireAdaptive()
+	if err != nil {
+		opts.logCanceled(method, url, 0, err, time.Since(start))
+		return
+	}
+	defer func() { releaseAdaptive(httpStatusCode, err) }()
 	resp, err := client.Do(req)
 	if err != nil {
+		err = attempts.wrap(req, err)
+		var dialErr *DialError
+		if opts.ctx.Err() != nil {
+			opts.logCanceled(method, url, 0, err, time.Since(start))
+		} else if errors.As(err, &dialErr) {
+			opts.logger.Error(opts.ctx, "HTTP_REQUEST_DIAL_ERROR", opts.withLoggerFields("method", method, "url", url, "host", dialErr.Host, "addrs", dialErr.Addrs, "err", dialErr.Err)...)
+		}
 		return
 	}
 	defer resp.Body.Close()
+	// 上下文结束时关闭响应体, 即使 RoundTripper 不响应上下文, 读取响应体也不会超过 deadline
+	stop := context.AfterFunc(opts.ctx, func() { resp.Body.Close() })
+	defer stop()
 	// 记录请求日志
 	dur := time.Since(start)
 	defer func() {
-		if reqOpts.slowThreshold > 0 && dur >= reqOpts.slowThreshold { // 超过 阈值 返回, 记一条 Warn 日志
-			reqOpts.logger.Warn(reqOpts.ctx, "HTTP_REQUEST_SLOW_LOG", "method", method, "url", url, "body", reqOpts.data, "reply", respBody, "err", err, "dur/ms", dur)
+		opts.captureResponse(httpStatusCode, header, time.Since(start))
+		if err != nil && opts.ctx.Err() != nil { // 读取响应体时超时或被取消
+			opts.logCanceled(method, url, httpStatusCode, err, time.Since(start))
+		} else if opts.slowThreshold > 0 && dur >= opts.slowThreshold && opts.longPoll == 0 { // 超过 阈值 返回, 记一条 Warn 日志, 长轮询除外
+			opts.logger.Warn(opts.ctx, "HTTP_REQUEST_SLOW_LOG", opts.requestLogFields(method, url, opts.data, respBody, header, err, dur)...)
 		} else {
-			reqOpts.logger.Debug(reqOpts.ctx, "HTTP_REQUEST_DEBUG_LOG", "method", method, "url", url, "body", string(reqOpts.data), "reply", string(respBody), "err", err, "dur/ms", dur)
+			opts.logger.Debug(opts.ctx, "HTTP_REQUEST_DEBUG_LOG", opts.requestLogFields(method, url, string(opts.data), string(respBody), header, err, dur)...)
 		}
 	}()
 
-	httpStatusCode = resp.StatusCode
-	if httpStatusCode != http.StatusOK {
-		// 返回非 200 时Go的 http 库不回返回error, 这里处理成error 调用方好判断
-		err = errors.New(fmt.Sprintf("non 200 response, response code: %d", httpStatusCode))
+	httpStatusCode, header = resp.StatusCode, resp.Header
+	if err = opts.checkHeaderCount(header); err != nil {
+		return
+	}
+	if httpStatusCode == http.StatusNotModified && revalidating { // 条件请求命中, 使用 ETagStore 中保存的响应体
+		respBody = cachedBody
+		return
+	}
+	if opts.onResponseHeaders != nil {
+		proceed, callbackErr := opts.onResponseHeaders(httpStatusCode, header)
+		if callbackErr != nil {
+			err = callbackErr
+			return
+		}
+		if !proceed { // 不读取响应体, 返回时直接关闭
+			if !opts.isSuccessStatus(httpStatusCode) {
+				err = &HTTPStatusError{StatusCode: httpStatusCode}
+			}
+			return
+		}
+	}
+	statusHandler, handled := opts.statusHandlers[httpStatusCode]
+	if !opts.isSuccessStatus(httpStatusCode) && !handled {
+		// 返回非 2xx 时Go的 http 库不回返回error, 这里处理成error 调用方好判断
+		// 同时返回响应体, 方便调用方查看错误详情
+		if body, decodeErr := decodeBody(resp); decodeErr == nil {
+			respBody = opts.readErrorBody(body)
+		}
+		err = &HTTPStatusError{StatusCode: httpStatusCode, Body: respBody}
 		return
 	}
 
-	respBody, _ = io.ReadAll(resp.Body)
+	if err = opts.checkContentLength(resp); err != nil {
+		return
+	}
+	digest, err := opts.wrapDigest(resp)
+	if err != nil {
+		return
+	}
+	body, err := decodeBody(resp)
+	if err != nil {
+		return
+	}
+	respBody, err = opts.readBody(body)
+	if err != nil {
+		// 读取响应体中途失败(如连接被断开导致的 unexpected EOF), 丢弃已读到的部分响应体
+		respBody = nil
+		if ctxErr := opts.ctx.Err(); ctxErr != nil {
+			err = ctxErr // 超时或取消导致的失败返回上下文的错误, 而不是关闭响应体产生的错误
+		}
+		return
+	}
+	if err = digest.verify(); err != nil {
+		respBody = nil // 摘要不一致的响应体不可信, 不返回给调用方
+		return
+	}
+	if respBody, err = opts.decryptResponseBody(respBody); err != nil {
+		return
+	}
+	if opts.bodyReencode != nil && len(respBody) > 0 {
+		if respBody, err = opts.bodyReencode(respBody); err != nil {
+			return
+		}
+	}
+	if handled {
+		if err = statusHandler(respBody, header); err != nil {
+			return
+		}
+	}
+	if err = opts.checkResponse(httpStatusCode, header, respBody); err != nil {
+		return
+	}
+	for _, extract := range opts.responseExtractors {
+		if err = extract(respBody); err != nil {
+			return
+		}
+	}
+	if httpStatusCode == http.StatusOK {
+		opts.storeETag(req, url, header, respBody)
+	}
 	return
 }
 
+// setHeaders 设置 WithHeaders(包括 WithBearerToken 等生成的 Authorization)和 WithRawHeader 的请求头
+func (opts *requestOption) setHeaders(req *http.Request) {
+	for key, value := range opts.headers {
+		req.Header.Add(key, value)
+	}
+	for _, h := range opts.rawHeaders { // 直接写入 map 绕过 Header.Add 的规范化, 写请求时按原样输出
+		req.Header[h[0]] = append(req.Header[h[0]], h[1])
+	}
+}
+
+// logCanceled 请求因超时或取消失败时记一条日志, 附带取消的原因, 避免被取消的请求在日志中不可见
+// 对冲请求中落败被取消的请求是预期内的, 只记 Debug 日志
+func (opts *requestOption) logCanceled(method string, url string, httpStatusCode int, err error, dur time.Duration) {
+	cause := context.Cause(opts.ctx)
+	fields := opts.withLoggerFields("method", method, "url", url, "status", httpStatusCode, "err", err, "cause", cause, "dur/ms", dur)
+	if errors.Is(cause, errHedgeLost) {
+		opts.logger.Debug(opts.ctx, "HTTP_REQUEST_CANCELED_LOG", fields...)
+		return
+	}
+	opts.logger.Warn(opts.ctx, "HTTP_REQUEST_CANCELED_LOG", fields...)
+}
+
+// shouldFallback 连接失败(没有拿到状态码)或服务端返回 5xx 时切换到下一个备用地址
+func shouldFallback(httpStatusCode int, err error) bool {
+	return err != nil && (httpStatusCode == 0 || httpStatusCode >= http.StatusInternalServerError)
+}
+
 // Get 发起GET请求
 func Get(ctx context.Context, url string, options ...Option) (httpStatusCode int, respBody []byte, err error) {
-	options = append(options, WithContext(ctx))
+	options = append(slices.Clip(options), WithContext(ctx))
 	return Request("GET", url, options...)
 }
 
 // Post 发起POST请求
 func Post(ctx context.Context, url string, data []byte, options ...Option) (httpStatusCode int, respBody []byte, err error) {
 	// 默认自带Header Content-Type: application/json 可通过 传递 WithHeaders 增加或者覆盖Header信息
+	return requestWithJSONBody(ctx, "POST", url, data, options...)
+}
+
+// Put 发起PUT请求, 与 Post 一样默认带 Content-Type: application/json
+func Put(ctx context.Context, url string, data []byte, options ...Option) (httpStatusCode int, respBody []byte, err error) {
+	return requestWithJSONBody(ctx, "PUT", url, data, options...)
+}
+
+// Patch 发起PATCH请求, 与 Post 一样默认带 Content-Type: application/json
+func Patch(ctx context.Context, url string, data []byte, options ...Option) (httpStatusCode int, respBody []byte, err error) {
+	return requestWithJSONBody(ctx, "PATCH", url, data, options...)
+}
+
+// Delete 发起DELETE请求, 不带请求体, 需要时可通过 WithData 设置
+func Delete(ctx context.Context, url string, options ...Option) (httpStatusCode int, respBody []byte, err error) {
+	options = append(slices.Clip(options), WithContext(ctx))
+	return Request("DELETE", url, options...)
+}
+
+// Head 发起HEAD请求, 响应没有响应体
+func Head(ctx context.Context, url string, options ...Option) (httpStatusCode int, respBody []byte, err error) {
+	options = append(slices.Clip(options), WithContext(ctx))
+	return Request("HEAD", url, options...)
+}
+
+// requestWithJSONBody 发起带JSON请求体的请求, 默认的 Content-Type 可通过 WithHeaders 覆盖
+func requestWithJSONBody(ctx context.Context, method string, url string, data []byte, options ...Option) (httpStatusCode int, respBody []byte, err error) {
 	defaultHeader := map[string]string{"Content-Type": "application/json"}
 	var newOptions []Option
 	newOptions = append(newOptions, WithHeaders(defaultHeader), WithData(data), WithContext(ctx))
 	newOptions = append(newOptions, options...)
+	return Request(method, url, newOptions...)
+}
+
+// PostForm 发起表单POST请求, 与 http.PostForm 一致, values 编码后作为请求体
+func PostForm(ctx context.Context, url string, values url.Values, options ...Option) (httpStatusCode int, respBody []byte, err error) {
+	// 默认自带Header Content-Type: application/x-www-form-urlencoded 可通过 传递 WithHeaders 覆盖
+	defaultHeader := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+	var newOptions []Option
+	newOptions = append(newOptions, WithHeaders(defaultHeader), WithData([]byte(values.Encode())), WithContext(ctx))
+	newOptions = append(newOptions, options...)
 
 	httpStatusCode, respBody, err = Request("POST", url, newOptions...)
 	return
 }
 
+// SupportsRangeRequests 通过HEAD请求探测服务端是否支持Range请求(断点续传), 同时返回资源总大小
+// 服务端未返回 Content-Length 时 size 为 -1
+func SupportsRangeRequests(ctx context.Context, url string, options ...Option) (supported bool, size int64, err error) {
+	options = append(slices.Clip(options), WithContext(ctx))
+	_, header, _, err := RequestWithResponse("HEAD", url, options...)
+	if err != nil {
+		return false, -1, err
+	}
+
+	supported = strings.EqualFold(strings.TrimSpace(header.Get("Accept-Ranges")), "bytes")
+	size = -1
+	if contentLength := header.Get("Content-Length"); contentLength != "" {
+		size, err = strconv.ParseInt(contentLength, 10, 64)
+		if err != nil {
+			return supported, -1, fmt.Errorf("invalid Content-Length %q: %w", contentLength, err)
+		}
+	}
+	return
+}
+
 // 针对可选的HTTP请求配置项，模仿gRPC使用的Options设计模式实现
 type requestOption struct {
 	ctx           context.Context
 	timeout       time.Duration
+	timeoutSet    bool // 是否通过 WithTimeout 等选项显式设置了超时
 	data          []byte
 	headers       map[string]string
+	rawHeaders    [][2]string // 保留大小写的请求头
 	logger        Interface
 	slowThreshold time.Duration // 慢请求阈值
+	validateJSON  bool          // 发送前校验JSON请求体
+	loggerFields  map[string]interface{}
+	transport     transportConfig // 需要派生 Transport 才能生效的选项
+	fallbackURLs  []string        // 主地址失败后依次尝试的备用地址
+	balancer      *Balancer
+
+	deadlinePropagationHeader string // 传递剩余超时时间的请求头
+	deadlineHeader            string // 传递截止时间的请求头
+	stats                     *Stats // 不为nil时收集请求统计信息
+	expectContentType         string // 期望的响应 Content-Type
+	bodyObserver              func(chunk []byte)
+	phaseTimeouts             *PhaseTimeouts // WithPhaseTimeouts 的设置, 用于检查配置是否合理
+	client                    *http.Client   // 不为nil时替代全局客户端, 如会话的客户端
+	// 不为nil时替代 data 生成请求体, 每次调用返回新的 Reader
+	bodyFunc func() (body io.Reader, contentLength int64, err error)
+	// bodyFunc 只能调用一次, 如 WithBodyReader 传入不支持 Seek 的 Reader
+	bodyNotReplayable bool
+	// bodyFunc 每次返回同一个 io.ReadSeeker, 不能同时发送多个请求, 如 WithBodyReader 传入不支持 ReadAt 的 Reader
+	bodySharedSeeker bool
+
+	stackOnError bool // 请求出错时记录调用栈
+
+	authorization string // WithBasicAuth 或 WithBearerToken 生成的 Authorization 请求头
+
+	validateContentLength bool // 校验读到的响应体字节数与 Content-Length 一致
+
+	onResponseHeaders func(httpStatusCode int, header http.Header) (proceed bool, err error) // 收到响应头后、读取响应体前调用
+
+	maxResponseHeaderCount int // 响应头字段数的上限
+
+	encryptBody func(plaintext []byte) ([]byte, error)  // 发送前加密请求体
+	decryptBody func(ciphertext []byte) ([]byte, error) // 读取后解密响应体
+
+	verifyDigest bool // 按 Digest 响应头校验响应体
+
+	preconnectProbe string // Preconnect 改为发送这个方法的请求建立连接
+
+	etagStore ETagStore
+
+	logRequestBody  bool // 请求日志中是否输出请求体
+	logResponseBody bool // 请求日志中是否输出响应体
+
+	logResponseHeaders []string // 请求日志中输出的响应头
+	responseLogBytes   int      // 请求日志中最多输出的响应体字节数, <= 0 表示不限制
+
+	compressToFile  string   // 响应体压缩后写入的文件路径
+	requiredHeaders []string // 发送前必须已设置的请求头
+
+	maxResponseBytes int64 // 响应体的最大字节数, 0 表示不限制
+	bodyReadLimit    int64 // 响应体最多读取的字节数, 超出部分丢弃
+
+	responseExtractors []func(respBody []byte) error // 从响应体中提取数据, 如 WithJSONPath
+	responseValidators []ResponseValidator
+	statusHandlers     map[int]func(respBody []byte, header http.Header) error // 按状态码处理响应
+	expectedStatus     map[int]bool                                            // 视为成功的状态码, nil 时为 2xx
+	bodyReencode       func(respBody []byte) ([]byte, error)                   // 转换响应体的格式
+	errorHeader        string                                                  // 表示请求失败的响应头
+
+	idempotencyOperationID string // 生成幂等键的操作ID
+
+	tenant   string        // WithTenant 设置的租户
+	longPoll time.Duration // 长轮询的最长等待时间
+
+	hedgeAfter time.Duration // 发出对冲请求前等待的时间, 0 表示不对冲
+	maxHedges  int           // 最多额外发出的对冲请求数
+
+	bodyConsumer  func(ctx context.Context, r io.Reader) error // 流式处理响应体, 如 StreamNDJSON
+	bodyHandedOut bool                                         // 响应体已经交给 bodyConsumer 或 bodyObserver, 之后不再重试或尝试备用地址
+
+	dedupeKey    string        // 去重的key
+	dedupeWindow time.Duration // 去重的时间窗口
+
+	bodyPool bool // 响应体读到复用的缓冲区中
+
+	adaptiveLimiter *AdaptiveLimiter // WithAdaptiveLimit 设置的自适应限流器
+
+	cassette *cassette // WithRecord 或 WithReplay 使用的录制文件
+	replay   bool      // 是否回放, false 为录制
+
+	retryAttempts  int           // 最多发送的次数, 包括第一次
+	retryBaseDelay time.Duration // 第一次重试前的等待时间, 之后每次翻倍
+	retryJitter    bool          // 等待时间是否加随机抖动
+	retryMaxDelay  time.Duration // 每次重试等待时间的上限, 0 时使用 maxRetryDelay
+	retryStatus    []int         // 触发重试的状态码, nil 时使用默认值
+
+	trailerKeys []string          // WithRequestTrailer 声明的 trailer
+	trailerFill func(http.Header) // 请求体发送完之后填充 trailer
 }
 
 type Option interface {
@@ -144,6 +576,9 @@ func defaultRequestOptions() *requestOption {
 		data:    nil,
 		headers: map[string]string{},
 		logger:  Default,
+
+		logRequestBody:  true,
+		logResponseBody: true,
 	}
 }
 
@@ -154,14 +589,19 @@ func WithContext(ctx context.Context) Option {
 	})
 }
 
+// WithTimeout 设置请求超时时间, 与上下文的 deadline 同时存在时以较早的为准
+// 不设置时, 上下文有 deadline 则只使用上下文的 deadline, 否则默认 5 秒
 func WithTimeout(timeout time.Duration) Option {
 	return optionFunc(func(opts *requestOption) (err error) {
-		opts.timeout, err = timeout, nil
+		opts.timeout, opts.timeoutSet, err = timeout, true, nil
 		return
 	})
 }
 
+// WithHeaders 设置请求头, 多次设置时合并
+// 创建选项时会拷贝 headers, 之后修改 headers 不影响已创建的选项, 同一个选项可以在多个 goroutine 中复用
 func WithHeaders(headers map[string]string) Option {
+	headers = maps.Clone(headers)
 	return optionFunc(func(opts *requestOption) (err error) {
 		for k, v := range headers {
 			opts.headers[k] = v
@@ -170,6 +610,15 @@ func WithHeaders(headers map[string]string) Option {
 	})
 }
 
+// WithRawHeader 设置请求头并保留 key 的大小写, 不做 X-Api-Key 这样的规范化, 用于兼容按大小写解析请求头的服务端
+// 只对 HTTP/1.x 有效, HTTP/2 协议要求请求头名称小写
+func WithRawHeader(key string, value string) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.rawHeaders = append(opts.rawHeaders, [2]string{key, value})
+		return
+	})
+}
+
 func WithData(data []byte) Option {
 	return optionFunc(func(opts *requestOption) (err error) {
 		opts.data, err = data, nil
@@ -184,6 +633,109 @@ func WithLogger(l Interface) Option {
 	})
 }
 
+// WithLoggerFields 给本次请求输出的所有日志附加固定字段, 如 service=checkout, 多次设置时合并
+func WithLoggerFields(fields map[string]interface{}) Option {
+	fields = maps.Clone(fields) // 与 WithHeaders 一样拷贝一份, 避免调用方之后修改 fields
+	return optionFunc(func(opts *requestOption) (err error) {
+		if opts.loggerFields == nil {
+			opts.loggerFields = make(map[string]interface{}, len(fields))
+		}
+		for k, v := range fields {
+			opts.loggerFields[k] = v
+		}
+		return
+	})
+}
+
+// WithFallbackURLs 设置备用地址, 主地址连接失败或返回 5xx 时按顺序尝试备用地址
+// 所有地址共用 WithTimeout 设置的超时时间
+func WithFallbackURLs(urls ...string) Option {
+	urls = slices.Clone(urls)
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.fallbackURLs = append(opts.fallbackURLs, urls...)
+		return
+	})
+}
+
+// WithLogRequestBody 设置请求日志(debug/慢请求)中是否输出请求体, 默认输出
+func WithLogRequestBody(enabled bool) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.logRequestBody, err = enabled, nil
+		return
+	})
+}
+
+// WithLogResponseBody 设置请求日志(debug/慢请求)中是否输出响应体, 默认输出
+func WithLogResponseBody(enabled bool) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.logResponseBody, err = enabled, nil
+		return
+	})
+}
+
+// WithResponseLogBytes 请求日志(debug/慢请求)中最多输出响应体的前 n 个字节, n <= 0 表示不限制
+// 只影响日志, 返回给调用方的响应体仍然是完整的; 限制返回的响应体请使用 WithMaxResponseBytes 或 WithBodyReadLimit
+func WithResponseLogBytes(n int) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.responseLogBytes = n
+		return
+	})
+}
+
+// responseBodyLogValue 日志中输出的响应体, 超过 WithResponseLogBytes 的部分截掉并注明总长度
+func (opts *requestOption) responseBodyLogValue(respBody interface{}) interface{} {
+	var body string
+	switch b := respBody.(type) {
+	case []byte:
+		body = string(b)
+	case string:
+		body = b
+	}
+	n := opts.responseLogBytes
+	if n <= 0 || len(body) <= n {
+		return respBody
+	}
+	for n > 0 && !utf8.RuneStart(body[n]) { // 不要截断在多字节字符中间
+		n--
+	}
+	return fmt.Sprintf("%s...(truncated, %d bytes)", body[:n], len(body))
+}
+
+// WithLogResponseHeaders 在请求日志(debug/慢请求)中输出指定的响应头, 如 X-Request-ID、X-Cache, 便于和服务端日志关联
+// 只输出指定的且响应中存在的响应头; Set-Cookie 等敏感响应头的值会被替换为 [REDACTED]
+func WithLogResponseHeaders(keys ...string) Option {
+	keys = slices.Clone(keys)
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.logResponseHeaders = append(opts.logResponseHeaders, keys...)
+		return
+	})
+}
+
+// sensitiveHeaders 日志中不输出值的请求头和响应头
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+}
+
+// responseHeaderLogValue 日志中输出的响应头, 敏感响应头的值替换为 [REDACTED]
+func (opts *requestOption) responseHeaderLogValue(header http.Header) map[string]string {
+	values := make(map[string]string, len(opts.logResponseHeaders))
+	for _, key := range opts.logResponseHeaders {
+		key = http.CanonicalHeaderKey(key)
+		if value := header.Values(key); len(value) > 0 {
+			if sensitiveHeaders[key] {
+				values[key] = "[REDACTED]"
+			} else {
+				values[key] = strings.Join(value, ", ")
+			}
+		}
+	}
+	return values
+}
+
 // WithSlowThreshold 设置慢请求阈值 单位:毫秒
 func WithSlowThreshold(threshold time.Duration) Option {
 	return optionFunc(func(opts *requestOption) (err error) {
@@ -191,3 +743,63 @@ func WithSlowThreshold(threshold time.Duration) Option {
 		return
 	})
 }
+
+// WithValidateJSON 发送前校验请求体是否为合法JSON, 仅在 Content-Type 为JSON类型时生效
+func WithValidateJSON() Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.validateJSON = true
+		return
+	})
+}
+
+// requestLogFields 请求日志的字段, 请求体和响应体按 WithLogRequestBody/WithLogResponseBody 的设置决定是否输出
+func (opts *requestOption) requestLogFields(method string, url string, reqBody interface{}, respBody interface{}, header http.Header, err error, dur time.Duration) []interface{} {
+	data := []interface{}{"method", method, "url", url}
+	if opts.logRequestBody {
+		data = append(data, "body", reqBody)
+	}
+	if opts.logResponseBody {
+		data = append(data, "reply", opts.responseBodyLogValue(respBody))
+	}
+	if len(opts.logResponseHeaders) > 0 && header != nil {
+		data = append(data, "reply_headers", opts.responseHeaderLogValue(header))
+	}
+	data = append(data, "err", err, "dur/ms", dur)
+	return opts.withLoggerFields(data...)
+}
+
+// withLoggerFields 在请求日志字段后追加 WithLoggerFields 设置的固定字段, 按key排序保证输出稳定
+func (opts *requestOption) withLoggerFields(data ...interface{}) []interface{} {
+	if len(opts.loggerFields) == 0 {
+		return data
+	}
+	keys := make([]string, 0, len(opts.loggerFields))
+	for k := range opts.loggerFields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		data = append(data, k, opts.loggerFields[k])
+	}
+	return data
+}
+
+// headerValue 不区分大小写地从请求头map中取值
+func headerValue(headers map[string]string, key string) string {
+	key = http.CanonicalHeaderKey(key)
+	for k, v := range headers {
+		if http.CanonicalHeaderKey(k) == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// isJSONContentType 判断 Content-Type 是否为JSON类型, 包括 application/json 及 +json 后缀的类型
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
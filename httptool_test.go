@@ -1,12 +1,17 @@
 package httptool
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -60,15 +65,9 @@ func (e *errorResponseWriter) Write(p []byte) (int, error) {
 	return 0, errors.New("测试错误")
 }
 
-// 测试用例开始前重置客户端
-func resetClient() {
-	client = nil
-	once = sync.Once{}
-}
-
 // TestGetHttpClient 测试获取HTTP客户端
 func TestGetHttpClient(t *testing.T) {
-	resetClient()
+	ResetDefaultClient()
 
 	// 测试默认客户端
 	c1 := GetHttpClient()
@@ -85,7 +84,7 @@ func TestGetHttpClient(t *testing.T) {
 
 // TestSetHttpClient 测试设置自定义HTTP客户端
 func TestSetHttpClient(t *testing.T) {
-	resetClient()
+	ResetDefaultClient()
 
 	// 创建自定义客户端
 	customClient := &http.Client{
@@ -102,9 +101,56 @@ func TestSetHttpClient(t *testing.T) {
 	}
 }
 
+// TestResetDefaultClient 测试恢复默认客户端
+func TestResetDefaultClient(t *testing.T) {
+	customClient := &http.Client{Timeout: 30 * time.Second}
+	SetHttpClient(customClient)
+
+	ResetDefaultClient()
+	c := GetHttpClient()
+	if c == nil || c == customClient {
+		t.Fatal("ResetDefaultClient 后应重新创建默认客户端")
+	}
+	if _, ok := c.Transport.(*http.Transport); !ok {
+		t.Fatalf("默认客户端应使用 *http.Transport, 得到 %T", c.Transport)
+	}
+}
+
+// TestHttpClientConcurrent 测试并发设置和获取全局客户端, 需要配合 -race 运行
+func TestHttpClientConcurrent(t *testing.T) {
+	ResetDefaultClient()
+	defer ResetDefaultClient()
+
+	customClient := &http.Client{Timeout: 30 * time.Second}
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			SetHttpClient(customClient)
+		}()
+		go func() {
+			defer wg.Done()
+			if GetHttpClient() == nil {
+				t.Error("GetHttpClient 不应返回 nil")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			ResetDefaultClient()
+		}()
+	}
+	wg.Wait()
+
+	SetHttpClient(customClient)
+	if GetHttpClient() != customClient {
+		t.Fatal("并发调用结束后设置的客户端未生效")
+	}
+}
+
 // TestRequest 测试请求函数
 func TestRequest(t *testing.T) {
-	resetClient()
+	ResetDefaultClient()
 
 	// 创建测试服务器
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -116,6 +162,12 @@ func TestRequest(t *testing.T) {
 		case "/error":
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte(`{"status":"error"}`))
+		case "/status/201", "/status/204", "/status/299":
+			code, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/status/"))
+			w.WriteHeader(code)
+			if code != http.StatusNoContent {
+				w.Write([]byte(`{"status":"created"}`))
+			}
 		case "/headers":
 			// 测试请求头
 			if r.Header.Get("X-Test-Header") == "test-value" {
@@ -153,7 +205,7 @@ func TestRequest(t *testing.T) {
 
 	// 测试错误状态码的请求
 	t.Run("错误状态码", func(t *testing.T) {
-		statusCode, _, err := Request("GET", server.URL+"/error")
+		statusCode, body, err := Request("GET", server.URL+"/error")
 		if err == nil {
 			t.Fatal("期望错误但未获得")
 		}
@@ -163,8 +215,32 @@ func TestRequest(t *testing.T) {
 		if !strings.Contains(err.Error(), "non 200 response") {
 			t.Fatalf("错误消息不符合预期: %v", err)
 		}
+		if string(body) != `{"status":"error"}` {
+			t.Fatalf("非 2xx 响应也应返回响应体, 得到 %q", string(body))
+		}
+		var statusErr *HTTPStatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError || string(statusErr.Body) != `{"status":"error"}` {
+			t.Fatalf("期望 *HTTPStatusError 带有状态码和响应体, 得到 %#v", err)
+		}
 	})
 
+	// 测试 2xx 状态码都视为成功
+	for _, code := range []int{http.StatusCreated, http.StatusNoContent, 299} {
+		t.Run(fmt.Sprintf("状态码%d", code), func(t *testing.T) {
+			statusCode, body, err := Request("GET", server.URL+"/status/"+strconv.Itoa(code))
+			if err != nil {
+				t.Fatalf("2xx 响应不应返回错误: %v", err)
+			}
+			want := `{"status":"created"}`
+			if code == http.StatusNoContent {
+				want = ""
+			}
+			if statusCode != code || string(body) != want {
+				t.Fatalf("期望 %d %q, 得到 %d %q", code, want, statusCode, string(body))
+			}
+		})
+	}
+
 	// 测试请求头
 	t.Run("自定义请求头", func(t *testing.T) {
 		headers := map[string]string{"X-Test-Header": "test-value"}
@@ -217,7 +293,7 @@ func TestRequest(t *testing.T) {
 
 // TestGet 测试Get函数
 func TestGet(t *testing.T) {
-	resetClient()
+	ResetDefaultClient()
 
 	// 创建测试服务器
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -246,7 +322,7 @@ func TestGet(t *testing.T) {
 
 // TestPost 测试Post函数
 func TestPost(t *testing.T) {
-	resetClient()
+	ResetDefaultClient()
 
 	// 创建测试服务器
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -284,6 +360,183 @@ func TestPost(t *testing.T) {
 	}
 }
 
+// TestMethodHelpers 测试 Put、Patch、Delete、Head 函数
+func TestMethodHelpers(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Content-Type", r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(r.Method + " " + string(body)))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	data := []byte(`{"test":"data"}`)
+	tests := []struct {
+		name string
+		call func() (int, []byte, error)
+		want string
+	}{
+		{"Put", func() (int, []byte, error) { return Put(ctx, server.URL, data) }, `PUT {"test":"data"}`},
+		{"Patch", func() (int, []byte, error) { return Patch(ctx, server.URL, data) }, `PATCH {"test":"data"}`},
+		{"Delete", func() (int, []byte, error) { return Delete(ctx, server.URL) }, `DELETE `},
+		{"Head", func() (int, []byte, error) { return Head(ctx, server.URL) }, ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statusCode, body, err := tt.call()
+			if err != nil {
+				t.Fatalf("请求失败: %v", err)
+			}
+			if statusCode != http.StatusOK || string(body) != tt.want {
+				t.Fatalf("期望 200 %q, 得到 %d %q", tt.want, statusCode, string(body))
+			}
+		})
+	}
+
+	// Put 和 Patch 默认带 JSON 的 Content-Type, 可以通过 WithHeaders 覆盖
+	var contentType string
+	client := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		contentType = r.Header.Get("Content-Type")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Header: http.Header{}, Request: r}, nil
+	})}
+	SetHttpClient(client)
+	defer ResetDefaultClient()
+	if Patch(ctx, "http://example.com", data); contentType != "application/json" {
+		t.Fatalf("Patch 默认 Content-Type 应为 application/json, 得到 %q", contentType)
+	}
+	if Put(ctx, "http://example.com", data, WithHeaders(map[string]string{"Content-Type": "text/plain"})); contentType != "text/plain" {
+		t.Fatalf("Content-Type 应可被覆盖, 得到 %q", contentType)
+	}
+	if Delete(ctx, "http://example.com"); contentType != "" {
+		t.Fatalf("Delete 不应设置 Content-Type, 得到 %q", contentType)
+	}
+}
+
+// TestRequestWithResponse 测试返回响应头
+func TestRequestWithResponse(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/created/1")
+		w.Header().Set("X-RateLimit-Remaining", "9")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	ctx := ContextWithResponseCapture(context.Background())
+	statusCode, header, body, err := RequestWithResponse(http.MethodGet, server.URL, WithContext(ctx))
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if statusCode != http.StatusOK || string(body) != "ok" || header.Get("Location") != "/created/1" || header.Get("X-RateLimit-Remaining") != "9" {
+		t.Fatalf("响应不符合预期: %d %q %v", statusCode, string(body), header)
+	}
+	// 修改返回的响应头不影响上下文中记录的响应头
+	header.Set("Location", "changed")
+	if info, _ := ResponseFromContext(ctx); info.Header.Get("Location") != "/created/1" {
+		t.Fatalf("上下文中的响应头被修改: %v", info.Header)
+	}
+
+	statusCode, header, _, err = RequestWithResponse(http.MethodGet, server.URL+"/missing")
+	if err == nil || statusCode != http.StatusNotFound || header.Get("Location") != "/created/1" {
+		t.Fatalf("非 200 响应期望返回错误和响应头, 得到 %d %v %v", statusCode, header, err)
+	}
+}
+
+// TestPostForm 测试PostForm函数
+func TestPostForm(t *testing.T) {
+	ResetDefaultClient()
+
+	// 创建测试服务器
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.PostForm.Get("name") == "张三" && r.PostForm.Get("age") == "25" {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"method":"post-form"}`))
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	// 测试PostForm请求
+	ctx := context.Background()
+	values := url.Values{}
+	values.Set("name", "张三")
+	values.Set("age", "25")
+	statusCode, body, err := PostForm(ctx, server.URL, values)
+	if err != nil {
+		t.Fatalf("PostForm请求失败: %v", err)
+	}
+	if statusCode != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusOK, statusCode)
+	}
+	if string(body) != `{"method":"post-form"}` {
+		t.Fatalf("期望响应体 %s, 得到 %s", `{"method":"post-form"}`, string(body))
+	}
+}
+
+// TestSupportsRangeRequests 测试Range请求支持探测
+func TestSupportsRangeRequests(t *testing.T) {
+	ResetDefaultClient()
+
+	// 创建测试服务器
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "HEAD" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.URL.Path == "/range" {
+			w.Header().Set("Accept-Ranges", "bytes")
+		}
+		w.Header().Set("Content-Length", "1024")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	t.Run("支持Range", func(t *testing.T) {
+		supported, size, err := SupportsRangeRequests(ctx, server.URL+"/range")
+		if err != nil {
+			t.Fatalf("探测失败: %v", err)
+		}
+		if !supported {
+			t.Fatal("期望支持Range请求")
+		}
+		if size != 1024 {
+			t.Fatalf("期望大小 %d, 得到 %d", 1024, size)
+		}
+	})
+
+	t.Run("不支持Range", func(t *testing.T) {
+		supported, size, err := SupportsRangeRequests(ctx, server.URL+"/plain")
+		if err != nil {
+			t.Fatalf("探测失败: %v", err)
+		}
+		if supported {
+			t.Fatal("期望不支持Range请求")
+		}
+		if size != 1024 {
+			t.Fatalf("期望大小 %d, 得到 %d", 1024, size)
+		}
+	})
+}
+
 // TestWithOptions 测试各种Option函数
 func TestWithOptions(t *testing.T) {
 	// 测试默认选项
@@ -368,14 +621,227 @@ func TestWithOptions(t *testing.T) {
 	}
 }
 
+// TestValidateJSON 测试发送前的JSON请求体校验
+func TestValidateJSON(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	t.Run("非法JSON", func(t *testing.T) {
+		_, _, err := Post(ctx, server.URL, []byte(`{"name":"张三",}`), WithValidateJSON())
+		if !errors.Is(err, ErrInvalidJSONBody) {
+			t.Fatalf("期望错误 %v, 得到 %v", ErrInvalidJSONBody, err)
+		}
+	})
+
+	t.Run("合法JSON", func(t *testing.T) {
+		_, _, err := Post(ctx, server.URL, []byte(`{"name":"张三"}`), WithValidateJSON())
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+	})
+
+	t.Run("非JSON类型不校验", func(t *testing.T) {
+		_, _, err := Post(ctx, server.URL, []byte(`name=张三`), WithValidateJSON(),
+			WithHeaders(map[string]string{"Content-Type": "text/plain; charset=utf-8"}))
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+	})
+}
+
+// TestReadBodyError 测试读取响应体失败时返回错误
+func TestReadBodyError(t *testing.T) {
+	ResetDefaultClient()
+
+	// 声明的 Content-Length 大于实际写入的长度, 模拟连接中途断开
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"partial":`))
+	}))
+	defer server.Close()
+
+	_, body, err := Get(context.Background(), server.URL)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("期望错误 %v, 得到 %v", io.ErrUnexpectedEOF, err)
+	}
+	if body != nil {
+		t.Fatalf("读取失败时不应返回部分响应体, 得到 %s", string(body))
+	}
+}
+
+// TestFallbackURLs 测试备用地址
+func TestFallbackURLs(t *testing.T) {
+	ResetDefaultClient()
+
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"region":"backup"}`))
+	}))
+	defer backup.Close()
+	// 已关闭的服务器, 模拟连接失败
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+
+	ctx := context.Background()
+
+	t.Run("5xx和连接失败后切换", func(t *testing.T) {
+		mockLogger := &MockLogger{}
+		statusCode, body, err := Get(ctx, primary.URL, WithFallbackURLs(down.URL, backup.URL), WithLogger(mockLogger))
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		if statusCode != http.StatusOK || string(body) != `{"region":"backup"}` {
+			t.Fatalf("期望使用备用地址的响应, 得到 %d %s", statusCode, string(body))
+		}
+		if !mockLogger.infoCalled || mockLogger.lastData[len(mockLogger.lastData)-1] != backup.URL {
+			t.Fatalf("应记录最终成功的地址, 得到 %v", mockLogger.lastData)
+		}
+	})
+
+	t.Run("主地址成功不切换, 全部失败返回最后一个错误", func(t *testing.T) {
+		statusCode, _, err := Get(ctx, backup.URL+"/", WithFallbackURLs(primary.URL))
+		if err != nil || statusCode != http.StatusOK {
+			t.Fatalf("主地址成功时不应切换, 得到 %d %v", statusCode, err)
+		}
+		statusCode, _, err = Get(ctx, down.URL, WithFallbackURLs(primary.URL))
+		if err == nil || statusCode != http.StatusServiceUnavailable {
+			t.Fatalf("期望最后一个地址的错误, 得到 %d %v", statusCode, err)
+		}
+	})
+}
+
+// TestRecoverPanic 测试自定义 RoundTripper panic 时转换成错误
+func TestRecoverPanic(t *testing.T) {
+	ResetDefaultClient()
+	defer ResetDefaultClient()
+
+	SetHttpClient(&http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		panic("middleware bug")
+	})})
+
+	_, _, err := Get(context.Background(), "http://example.com")
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("期望 PanicError, 得到 %v", err)
+	}
+	if panicErr.Value != "middleware bug" || len(panicErr.Stack) == 0 {
+		t.Fatalf("PanicError 内容不符合预期: %v", panicErr)
+	}
+}
+
+// TestReuseOptionsConcurrently 测试同一组选项在多个 goroutine 中复用, 需要配合 -race 运行
+func TestReuseOptionsConcurrently(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Test-Header") != "test-value" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	headers := map[string]string{"X-Test-Header": "test-value"}
+	fields := map[string]interface{}{"service": "checkout"}
+	options := []Option{
+		WithHeaders(headers),
+		WithLoggerFields(fields),
+		WithHeaders(map[string]string{"X-Another": "value"}),
+	}
+
+	GetHttpClient() // 先初始化全局客户端, 本用例只关注选项的复用
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := Get(context.Background(), server.URL, options...)
+			errs <- err
+		}()
+	}
+	// 创建选项之后调用方修改自己的 map 不应影响正在进行的请求
+	headers["X-Test-Header"] = "changed"
+	fields["service"] = "changed"
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("复用选项的请求失败: %v", err)
+		}
+	}
+}
+
+// TestReuseOptionsSpareCapacity 测试选项切片还有剩余容量时, 多个 goroutine 复用它也不会拿到别人的上下文, 需要配合 -race 运行
+func TestReuseOptionsSpareCapacity(t *testing.T) {
+	ResetDefaultClient()
+	defer ResetDefaultClient()
+
+	type idKey struct{}
+	// 把请求上下文中的 id 作为响应体返回, 拿到别的 goroutine 的上下文时 id 不一致
+	SetHttpClient(&http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		id, _ := r.Context().Value(idKey{}).(string)
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Accept-Ranges": {"bytes"}}, Body: io.NopCloser(strings.NewReader(id)), Request: r}, nil
+	})})
+
+	options := make([]Option, 1, 5)
+	options[0] = WithLogger(Default.LogMode(Silent))
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := strconv.Itoa(i)
+			ctx := context.WithValue(context.Background(), idKey{}, id)
+			for name, get := range map[string]func() ([]byte, error){
+				"Get": func() ([]byte, error) {
+					_, body, err := Get(ctx, "http://example.com", options...)
+					return body, err
+				},
+				"Delete": func() ([]byte, error) {
+					_, body, err := Delete(ctx, "http://example.com", options...)
+					return body, err
+				},
+			} {
+				if body, err := get(); err != nil || string(body) != id {
+					errs <- fmt.Errorf("%s 期望上下文 %s, 得到 %q %v", name, id, string(body), err)
+				}
+			}
+			if _, _, err := SupportsRangeRequests(ctx, "http://example.com", options...); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
 // TestLoggerOutputForRequest 测试请求日志输出
 func TestLoggerOutputForRequest(t *testing.T) {
-	resetClient()
+	ResetDefaultClient()
 
 	// 创建测试服务器
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case "/fast":
+			w.Header().Set("X-Request-Id", "req-1")
+			w.Header().Set("Set-Cookie", "session=secret")
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte(`{"response":"fast"}`))
 		case "/slow":
@@ -398,6 +864,73 @@ func TestLoggerOutputForRequest(t *testing.T) {
 		}
 	})
 
+	// 测试分别关闭请求体和响应体日志
+	t.Run("关闭请求体日志", func(t *testing.T) {
+		mockLogger := &MockLogger{}
+		_, _, _ = Request("GET", server.URL+"/fast", WithLogger(mockLogger), WithLogRequestBody(false))
+		keys := logKeys(mockLogger.lastData)
+		if keys["body"] || !keys["reply"] {
+			t.Fatalf("期望只输出响应体, 得到 %v", mockLogger.lastData)
+		}
+	})
+
+	t.Run("关闭响应体日志", func(t *testing.T) {
+		mockLogger := &MockLogger{}
+		_, _, _ = Request("GET", server.URL+"/fast", WithLogger(mockLogger), WithLogResponseBody(false))
+		keys := logKeys(mockLogger.lastData)
+		if !keys["body"] || keys["reply"] {
+			t.Fatalf("期望只输出请求体, 得到 %v", mockLogger.lastData)
+		}
+	})
+
+	t.Run("输出指定的响应头", func(t *testing.T) {
+		mockLogger := &MockLogger{}
+		_, _, _ = Request("GET", server.URL+"/fast", WithLogger(mockLogger), WithLogResponseHeaders("x-request-id", "Set-Cookie", "X-Cache"))
+		var headers map[string]string
+		for i := 0; i+1 < len(mockLogger.lastData); i += 2 {
+			if mockLogger.lastData[i] == "reply_headers" {
+				headers = mockLogger.lastData[i+1].(map[string]string)
+			}
+		}
+		if len(headers) != 2 || headers["X-Request-Id"] != "req-1" || headers["Set-Cookie"] != "[REDACTED]" {
+			t.Fatalf("期望输出 X-Request-Id 并隐藏 Set-Cookie, 得到 %v", headers)
+		}
+	})
+
+	t.Run("限制日志中的响应体长度", func(t *testing.T) {
+		mockLogger := &MockLogger{}
+		_, body, _ := Request("GET", server.URL+"/fast", WithLogger(mockLogger), WithResponseLogBytes(5))
+		var reply interface{}
+		for i := 0; i+1 < len(mockLogger.lastData); i += 2 {
+			if mockLogger.lastData[i] == "reply" {
+				reply = mockLogger.lastData[i+1]
+			}
+		}
+		if reply != `{"res...(truncated, 19 bytes)` {
+			t.Fatalf("期望日志中的响应体被截断, 得到 %v", reply)
+		}
+		if string(body) != `{"response":"fast"}` {
+			t.Fatalf("返回的响应体不应被截断, 得到 %q", string(body))
+		}
+	})
+
+	// 测试附加的固定日志字段
+	t.Run("固定日志字段", func(t *testing.T) {
+		mockLogger := &MockLogger{}
+		_, _, _ = Request("GET", server.URL+"/fast", WithLogger(mockLogger),
+			WithLoggerFields(map[string]interface{}{"service": "checkout", "endpoint": "create-order"}))
+		want := []interface{}{"endpoint", "create-order", "service", "checkout"}
+		got := mockLogger.lastData[len(mockLogger.lastData)-len(want):]
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("期望日志字段 %v, 得到 %v", want, got)
+			}
+		}
+		if mockLogger.lastData[0] != "method" {
+			t.Fatalf("固定字段应追加在请求字段之后, 得到 %v", mockLogger.lastData)
+		}
+	})
+
 	// 测试慢请求的日志
 	t.Run("慢请求日志", func(t *testing.T) {
 		mockLogger := &MockLogger{}
@@ -411,6 +944,17 @@ func TestLoggerOutputForRequest(t *testing.T) {
 	})
 }
 
+// logKeys 返回日志 key-value 字段中的 key
+func logKeys(data []interface{}) map[string]bool {
+	keys := map[string]bool{}
+	for i := 0; i+1 < len(data); i += 2 {
+		if k, ok := data[i].(string); ok {
+			keys[k] = true
+		}
+	}
+	return keys
+}
+
 // TestNewRequestError 测试创建请求对象时的错误
 func TestNewRequestError(t *testing.T) {
 	_, _, err := Request("INVALID_METHOD", "http://example.com")
@@ -435,3 +979,145 @@ func TestContextTimeout(t *testing.T) {
 		t.Fatal("上下文超时应该返回错误")
 	}
 }
+
+// TestTimeoutPrecedence 测试默认超时、WithTimeout 和上下文 deadline 的优先级
+func TestTimeoutPrecedence(t *testing.T) {
+	var deadline time.Time
+	SetHttpClient(&http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		deadline, _ = r.Context().Deadline()
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: r}, nil
+	})})
+	defer ResetDefaultClient()
+
+	ctxDeadline := time.Now().Add(30 * time.Second)
+	ctx, cancel := context.WithDeadline(context.Background(), ctxDeadline)
+	defer cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		options []Option
+		want    time.Duration // 相对于发起请求的时间, 0 表示使用上下文的 deadline
+	}{
+		{"没有deadline时使用默认超时", context.Background(), nil, 5 * time.Second},
+		{"上下文的deadline优先于默认超时", ctx, nil, 0},
+		{"WithTimeout更短时生效", ctx, []Option{WithTimeout(time.Second)}, time.Second},
+		{"上下文的deadline更短时生效", ctx, []Option{WithTimeout(time.Minute)}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			if _, _, err := Get(tt.ctx, "http://example.com", tt.options...); err != nil {
+				t.Fatalf("请求失败: %v", err)
+			}
+			want := ctxDeadline
+			if tt.want > 0 {
+				want = start.Add(tt.want)
+			}
+			if diff := deadline.Sub(want); diff < -100*time.Millisecond || diff > 100*time.Millisecond {
+				t.Fatalf("期望 deadline %v, 得到 %v", want, deadline)
+			}
+		})
+	}
+}
+
+// TestRequestContextReleased 测试请求返回后内部的超时上下文被释放, 且不影响读取响应体
+func TestRequestContextReleased(t *testing.T) {
+	var reqCtx context.Context
+	SetHttpClient(&http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		reqCtx = r.Context()
+		// 分两次返回响应体, 读取时上下文必须仍然有效
+		body := io.MultiReader(strings.NewReader("hello "), strings.NewReader("world"))
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(body), ContentLength: -1, Request: r}, nil
+	})})
+	defer ResetDefaultClient()
+
+	_, body, err := Get(context.Background(), "http://example.com", WithTimeout(time.Hour))
+	if err != nil || string(body) != "hello world" {
+		t.Fatalf("期望读到完整的响应体, 得到 %q %v", string(body), err)
+	}
+	if reqCtx.Err() == nil {
+		t.Fatal("请求返回后内部的超时上下文应该被取消")
+	}
+}
+
+// TestWithRawHeader 测试请求头按原样的大小写发送
+func TestWithRawHeader(t *testing.T) {
+	ResetDefaultClient()
+
+	// http.Server 解析请求头时会规范化, 这里直接读原始请求
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var got []string
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil || line == "\r\n" {
+				break
+			}
+			got = append(got, strings.TrimSpace(line))
+		}
+		conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\nConnection: close\r\n\r\n"))
+		lines <- got
+	}()
+
+	_, _, err = Get(context.Background(), "http://"+ln.Addr().String(), WithRawHeader("x-api-key", "secret"))
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	got := <-lines
+	found := false
+	for _, line := range got {
+		if line == "x-api-key: secret" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("期望原样发送 x-api-key, 得到 %q", got)
+	}
+}
+
+// TestLogCanceledRequest 测试被取消的请求也会记录日志
+func TestLogCanceledRequest(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/body" {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	for _, path := range []string{"/header", "/body"} {
+		t.Run("等待"+path, func(t *testing.T) {
+			logger := &MockLogger{}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, _, err := Get(ctx, server.URL+path, WithLogger(logger))
+			if err == nil {
+				t.Fatal("期望请求超时")
+			}
+			if !logger.warnCalled || logger.lastMsg != "HTTP_REQUEST_CANCELED_LOG" {
+				t.Fatalf("期望记录取消日志, 得到 %q", logger.lastMsg)
+			}
+			if keys := logKeys(logger.lastData); !keys["cause"] || !keys["dur/ms"] {
+				t.Fatalf("取消日志应包含原因和耗时, 得到 %v", logger.lastData)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Package httptooltest 提供测试使用 httptool 的代码时用到的工具, 如伪造响应、记录请求并断言
+package httptooltest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/jayzyc/httptool"
+)
+
+// RoundTripFunc 用函数实现 http.RoundTripper
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip 实现 http.RoundTripper
+func (f RoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// RespondWith 返回固定状态码和响应体的 RoundTripFunc
+func RespondWith(statusCode int, body string) RoundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			Status:        strconv.Itoa(statusCode) + " " + http.StatusText(statusCode),
+			StatusCode:    statusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{},
+			Body:          io.NopCloser(bytes.NewBufferString(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+}
+
+// CapturedRequest 记录下来的请求
+type CapturedRequest struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// recorder 记录请求, 供 Transport 和 MockServer 共用
+type recorder struct {
+	mu       sync.Mutex
+	requests []CapturedRequest
+}
+
+func (r *recorder) record(req *http.Request) error {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body)) // 记录后还原请求体, 后续处理仍可读取
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, CapturedRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header.Clone(),
+		Body:   body,
+	})
+	return nil
+}
+
+// Requests 返回按顺序记录的全部请求
+func (r *recorder) Requests() []CapturedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]CapturedRequest(nil), r.requests...)
+}
+
+// LastRequest 返回最后一个请求, 还没有请求时 ok 为 false
+func (r *recorder) LastRequest() (req CapturedRequest, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.requests) == 0 {
+		return CapturedRequest{}, false
+	}
+	return r.requests[len(r.requests)-1], true
+}
+
+// Transport 记录经过的请求并交给 fn 生成响应, 不发生真实的网络请求
+type Transport struct {
+	recorder
+	fn RoundTripFunc
+}
+
+// NewTransport 创建 Transport
+func NewTransport(fn RoundTripFunc) *Transport {
+	return &Transport{fn: fn}
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.record(req); err != nil {
+		return nil, err
+	}
+	return t.fn(req)
+}
+
+// Install 把使用 rt 的客户端设置为 httptool 的全局客户端, 返回恢复原客户端的函数, 通常配合 defer 或 t.Cleanup 使用
+func Install(rt http.RoundTripper) (restore func()) {
+	previous := httptool.GetHttpClient()
+	httptool.SetHttpClient(&http.Client{Transport: rt})
+	return func() { httptool.SetHttpClient(previous) }
+}
+
+// MockServer 记录请求的测试服务器, 适合需要真实网络连接的场景(如测试 Transport 相关的选项)
+type MockServer struct {
+	*httptest.Server
+	recorder
+}
+
+// NewMockServer 启动测试服务器, 请求记录后交给 handler 处理, 用完后调用 Close
+func NewMockServer(handler http.Handler) *MockServer {
+	s := &MockServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := s.record(r); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	return s
+}
+
+// AssertRequest 断言请求的方法和请求头, headers 中只列出需要检查的请求头
+func AssertRequest(t testing.TB, req CapturedRequest, method string, headers map[string]string) {
+	t.Helper()
+	if req.Method != method {
+		t.Errorf("期望请求方法 %s, 得到 %s", method, req.Method)
+	}
+	for k, v := range headers {
+		if got := req.Header.Get(k); got != v {
+			t.Errorf("期望请求头 %s 为 %q, 得到 %q", k, v, got)
+		}
+	}
+}
+
+// AssertBody 断言请求体
+func AssertBody(t testing.TB, req CapturedRequest, body string) {
+	t.Helper()
+	if string(req.Body) != body {
+		t.Errorf("期望请求体 %q, 得到 %q", body, string(req.Body))
+	}
+}
@@ -0,0 +1,51 @@
+package httptooltest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/jayzyc/httptool"
+)
+
+// TestTransport 测试伪造响应并断言请求
+func TestTransport(t *testing.T) {
+	tr := NewTransport(RespondWith(http.StatusOK, `{"ok":true}`))
+	defer Install(tr)()
+
+	statusCode, body, err := httptool.Post(context.Background(), "http://api.example.com/users", []byte(`{"name":"张三"}`))
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if statusCode != http.StatusOK || string(body) != `{"ok":true}` {
+		t.Fatalf("期望伪造的响应, 得到 %d %s", statusCode, string(body))
+	}
+
+	req, ok := tr.LastRequest()
+	if !ok {
+		t.Fatal("应记录请求")
+	}
+	AssertRequest(t, req, "POST", map[string]string{"Content-Type": "application/json"})
+	AssertBody(t, req, `{"name":"张三"}`)
+	if req.URL != "http://api.example.com/users" {
+		t.Fatalf("期望请求地址 %s, 得到 %s", "http://api.example.com/users", req.URL)
+	}
+}
+
+// TestMockServer 测试记录请求的测试服务器
+func TestMockServer(t *testing.T) {
+	server := NewMockServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	httptool.Get(ctx, server.URL+"/a")
+	httptool.Get(ctx, server.URL+"/b", httptool.WithHeaders(map[string]string{"X-Test": "1"}))
+
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("期望记录 2 个请求, 得到 %d", len(requests))
+	}
+	AssertRequest(t, requests[1], "GET", map[string]string{"X-Test": "1"})
+}
@@ -0,0 +1,52 @@
+package httptool
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+// WithIdempotencyKey 根据 operationID 和请求体的哈希生成确定的幂等键, 通过 Idempotency-Key 请求头发送
+// 同一个逻辑操作(相同的 operationID 和请求体)无论是进程内重试还是崩溃重启后重发, 幂等键都相同, 服务端可据此去重
+// 设置了 WithStats 时生成的幂等键写入 Stats.IdempotencyKey, 方便调用方持久化
+func WithIdempotencyKey(operationID string) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		if operationID == "" {
+			return errors.New("idempotency operation id must not be empty")
+		}
+		opts.idempotencyOperationID = operationID
+		return
+	})
+}
+
+// setIdempotencyKey 在全部选项应用完之后计算幂等键, 保证请求体已经确定
+func (opts *requestOption) setIdempotencyKey() error {
+	if opts.idempotencyOperationID == "" {
+		return nil
+	}
+	// 计算幂等键需要读一遍请求体, 只能读一次的请求体读完就发不出去了
+	if opts.bodyNotReplayable {
+		return ErrBodyNotReplayable
+	}
+	h := sha256.New()
+	h.Write([]byte(opts.idempotencyOperationID))
+	h.Write([]byte{0}) // 分隔 operationID 和请求体, 避免拼接后产生歧义
+	if opts.bodyFunc != nil {
+		body, _, err := opts.bodyFunc()
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(h, body); err != nil {
+			return err
+		}
+	} else {
+		h.Write(opts.data)
+	}
+	key := hex.EncodeToString(h.Sum(nil))
+	opts.headers["Idempotency-Key"] = key
+	if opts.stats != nil {
+		opts.stats.IdempotencyKey = key
+	}
+	return nil
+}
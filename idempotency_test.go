@@ -0,0 +1,46 @@
+package httptool
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestWithIdempotencyKey 测试确定性的幂等键
+func TestWithIdempotencyKey(t *testing.T) {
+	ResetDefaultClient()
+
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get("Idempotency-Key"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	post := func(operationID string, data string) string {
+		var stats Stats
+		if _, _, err := Post(ctx, server.URL, []byte(data), WithIdempotencyKey(operationID), WithStats(&stats)); err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		if stats.IdempotencyKey != received[len(received)-1] {
+			t.Fatalf("Stats 中的幂等键 %q 与发送的 %q 不一致", stats.IdempotencyKey, received[len(received)-1])
+		}
+		return stats.IdempotencyKey
+	}
+
+	first := post("order-1", `{"amount":1}`)
+	if first == "" {
+		t.Fatal("期望发送幂等键")
+	}
+	if again := post("order-1", `{"amount":1}`); again != first {
+		t.Fatalf("同一操作重发时幂等键应相同, 得到 %q 和 %q", first, again)
+	}
+	if other := post("order-2", `{"amount":1}`); other == first {
+		t.Fatal("不同的操作应生成不同的幂等键")
+	}
+	if other := post("order-1", `{"amount":2}`); other == first {
+		t.Fatal("请求体不同时应生成不同的幂等键")
+	}
+}
@@ -0,0 +1,40 @@
+package httptool
+
+import (
+	"context"
+	"sync"
+)
+
+var (
+	inFlightMu  sync.RWMutex
+	inFlightSem chan struct{} // 全局在途请求数的信号量, nil 表示不限制
+)
+
+// SetMaxInFlight 限制整个进程同时在途的请求数, 用于流量突增时防止耗尽文件描述符, n <= 0 表示不限制
+// 与按 host 的连接数限制不同, 这里限制的是所有请求的总数; 等待名额时会响应请求上下文的取消
+func SetMaxInFlight(n int) {
+	inFlightMu.Lock()
+	defer inFlightMu.Unlock()
+	if n <= 0 {
+		inFlightSem = nil
+		return
+	}
+	inFlightSem = make(chan struct{}, n)
+}
+
+// acquireInFlight 获取一个在途请求名额, 请求结束后调用 release 归还
+func acquireInFlight(ctx context.Context) (release func(), err error) {
+	inFlightMu.RLock()
+	sem := inFlightSem // 归还到获取时的信号量, 避免 SetMaxInFlight 替换信号量后归还错位
+	inFlightMu.RUnlock()
+	if sem == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
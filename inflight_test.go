@@ -0,0 +1,61 @@
+package httptool
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestSetMaxInFlight 测试全局在途请求数限制
+func TestSetMaxInFlight(t *testing.T) {
+	ResetDefaultClient()
+	SetMaxInFlight(2)
+	defer SetMaxInFlight(0)
+
+	var current, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		defer current.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	GetHttpClient() // 先初始化全局客户端, 本用例只关注在途请求数
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := Get(context.Background(), server.URL); err != nil {
+				t.Errorf("请求失败: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if peak.Load() > 2 {
+		t.Fatalf("在途请求数不应超过 2, 得到 %d", peak.Load())
+	}
+
+	// 名额被占满时等待过程中上下文超时
+	release, _ := acquireInFlight(context.Background())
+	release2, _ := acquireInFlight(context.Background())
+	defer release()
+	defer release2()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := Get(ctx, server.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("期望等待名额时超时, 得到 %v", err)
+	}
+}
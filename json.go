@@ -0,0 +1,49 @@
+package httptool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrEncodeRequest PostJSON 序列化请求体失败时返回, 此时请求不会发出
+	ErrEncodeRequest = errors.New("encode json request")
+	// ErrDecodeResponse PostJSON、GetJSON 解析响应体失败时返回, 此时请求已经成功完成
+	ErrDecodeResponse = errors.New("decode json response")
+)
+
+// PostJSON 把 reqStruct 序列化为 JSON 发起 POST 请求(带 Content-Type: application/json), 并把响应体解析到 respStruct
+// respStruct 为 nil 或响应体为空(如 204)时不解析; 序列化和解析失败分别返回包装了 ErrEncodeRequest 和 ErrDecodeResponse 的错误,
+// 可以用 errors.Is 与请求本身的错误(如 *HTTPStatusError)区分, 请求失败时不解析响应体
+func PostJSON(ctx context.Context, url string, reqStruct interface{}, respStruct interface{}, options ...Option) (httpStatusCode int, err error) {
+	data, err := json.Marshal(reqStruct)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrEncodeRequest, err)
+	}
+	httpStatusCode, respBody, err := Post(ctx, url, data, options...)
+	if err != nil {
+		return
+	}
+	return httpStatusCode, decodeJSONResponse(respBody, respStruct)
+}
+
+// GetJSON 发起 GET 请求并把响应体解析到 respStruct, 解析规则与 PostJSON 相同
+func GetJSON(ctx context.Context, url string, respStruct interface{}, options ...Option) (httpStatusCode int, err error) {
+	httpStatusCode, respBody, err := Get(ctx, url, options...)
+	if err != nil {
+		return
+	}
+	return httpStatusCode, decodeJSONResponse(respBody, respStruct)
+}
+
+func decodeJSONResponse(respBody []byte, respStruct interface{}) error {
+	if respStruct == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, respStruct); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodeResponse, err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package httptool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestPostJSONAndGetJSON 测试 JSON 请求和响应的序列化
+func TestPostJSONAndGetJSON(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			var req map[string]string
+			if r.Header.Get("Content-Type") != "application/json" || json.NewDecoder(r.Body).Decode(&req) != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"hello": req["name"]})
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		case "/html":
+			w.Write([]byte("<html></html>"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	var resp struct {
+		Hello string `json:"hello"`
+	}
+	statusCode, err := PostJSON(ctx, server.URL+"/echo", map[string]string{"name": "httptool"}, &resp)
+	if err != nil || statusCode != http.StatusOK || resp.Hello != "httptool" {
+		t.Fatalf("期望解析出响应, 得到 %d %+v %v", statusCode, resp, err)
+	}
+
+	if statusCode, err := GetJSON(ctx, server.URL+"/empty", &resp); err != nil || statusCode != http.StatusNoContent {
+		t.Fatalf("空响应体不应解析, 得到 %d %v", statusCode, err)
+	}
+
+	if _, err := PostJSON(ctx, server.URL+"/echo", make(chan int), nil); !errors.Is(err, ErrEncodeRequest) {
+		t.Fatalf("期望 ErrEncodeRequest, 得到 %v", err)
+	}
+
+	if _, err := GetJSON(ctx, server.URL+"/html", &resp); !errors.Is(err, ErrDecodeResponse) {
+		t.Fatalf("期望 ErrDecodeResponse, 得到 %v", err)
+	}
+
+	var statusErr *HTTPStatusError
+	if _, err := GetJSON(ctx, server.URL+"/missing", &resp); !errors.As(err, &statusErr) || errors.Is(err, ErrDecodeResponse) {
+		t.Fatalf("请求失败时期望 *HTTPStatusError, 得到 %v", err)
+	}
+}
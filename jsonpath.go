@@ -0,0 +1,60 @@
+package httptool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrJSONPathNotFound 响应体中不存在 WithJSONPath 指定的路径时返回
+var ErrJSONPathNotFound = errors.New("json path not found")
+
+// WithJSONPath 从JSON响应体中取出 path 处的值解析到 target, 不需要为整个响应定义结构体
+// path 用点分隔, 数组用下标, 如 "data.items.0.id"; 路径不存在时返回 ErrJSONPathNotFound, 请求仍然返回完整的响应体
+func WithJSONPath[T any](path string, target *T) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.responseExtractors = append(opts.responseExtractors, func(respBody []byte) error {
+			raw, err := lookupJSONPath(respBody, path)
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(raw, target); err != nil {
+				return fmt.Errorf("decode json path %q: %w", path, err)
+			}
+			return nil
+		})
+		return
+	})
+}
+
+// lookupJSONPath 按点分隔的路径逐层查找, 返回路径处的原始JSON
+func lookupJSONPath(data []byte, path string) (json.RawMessage, error) {
+	raw := json.RawMessage(data)
+	if path == "" {
+		return raw, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err == nil {
+			value, ok := object[key]
+			if !ok {
+				return nil, fmt.Errorf("%w: %q (missing key %q)", ErrJSONPathNotFound, path, key)
+			}
+			raw = value
+			continue
+		}
+		var array []json.RawMessage
+		if err := json.Unmarshal(raw, &array); err == nil {
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(array) {
+				return nil, fmt.Errorf("%w: %q (invalid index %q for array of length %d)", ErrJSONPathNotFound, path, key, len(array))
+			}
+			raw = array[index]
+			continue
+		}
+		return nil, fmt.Errorf("%w: %q (%q is not an object or array)", ErrJSONPathNotFound, path, key)
+	}
+	return raw, nil
+}
@@ -0,0 +1,47 @@
+package httptool
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestWithJSONPath 测试按路径提取JSON响应中的值
+func TestWithJSONPath(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data":{"token":"abc","items":[{"id":1},{"id":2}]}}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	t.Run("提取多个值", func(t *testing.T) {
+		var token string
+		var id int
+		_, body, err := Get(ctx, server.URL, WithJSONPath("data.token", &token), WithJSONPath("data.items.1.id", &id))
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		if token != "abc" || id != 2 {
+			t.Fatalf("期望 abc 和 2, 得到 %q 和 %d", token, id)
+		}
+		if len(body) == 0 {
+			t.Fatal("仍应返回完整的响应体")
+		}
+	})
+
+	for _, path := range []string{"data.missing", "data.items.5.id", "data.token.x"} {
+		t.Run("路径不存在 "+path, func(t *testing.T) {
+			var v interface{}
+			_, _, err := Get(ctx, server.URL, WithJSONPath(path, &v))
+			if !errors.Is(err, ErrJSONPathNotFound) {
+				t.Fatalf("期望 ErrJSONPathNotFound, 得到 %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package httptool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PostFile 以 multipart/form-data 上传单个文件, extraFields 为同时提交的普通表单字段
+// 文件的 Content-Type 优先按扩展名判断, 无法判断时根据文件内容探测
+func PostFile(ctx context.Context, url string, fieldName string, filePath string, extraFields map[string]string, options ...Option) (httpStatusCode int, respBody []byte, err error) {
+	data, contentType, err := buildFileForm(fieldName, filePath, extraFields)
+	if err != nil {
+		return
+	}
+
+	var newOptions []Option
+	newOptions = append(newOptions, WithData(data), WithContext(ctx))
+	newOptions = append(newOptions, options...)
+	// multipart 的 boundary 必须和请求体一致, 放在最后避免被覆盖
+	newOptions = append(newOptions, WithHeaders(map[string]string{"Content-Type": contentType}))
+
+	httpStatusCode, respBody, err = Request("POST", url, newOptions...)
+	return
+}
+
+// buildFileForm 构造包含一个文件和若干普通字段的 multipart 请求体
+func buildFileForm(fieldName string, filePath string, extraFields map[string]string) (data []byte, contentType string, err error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for k, v := range extraFields {
+		if err = w.WriteField(k, v); err != nil {
+			return
+		}
+	}
+
+	fileContentType, err := detectFileContentType(f)
+	if err != nil {
+		return
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(fieldName), escapeQuotes(filepath.Base(filePath))))
+	h.Set("Content-Type", fileContentType)
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return
+	}
+	if _, err = io.Copy(part, f); err != nil {
+		return
+	}
+	if err = w.Close(); err != nil {
+		return
+	}
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+// detectFileContentType 判断文件的 Content-Type, 探测内容后把读取位置恢复到文件开头
+func detectFileContentType(f *os.File) (string, error) {
+	if contentType := mime.TypeByExtension(filepath.Ext(f.Name())); contentType != "" {
+		return contentType, nil
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// escapeQuotes 与 mime/multipart 中对 Content-Disposition 参数的转义方式保持一致
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
@@ -0,0 +1,55 @@
+package httptool
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestPostFile 测试单文件上传
+func TestPostFile(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f, fh, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+		content, _ := io.ReadAll(f)
+		if string(content) != `{"name":"张三"}` || fh.Filename != "data.json" ||
+			fh.Header.Get("Content-Type") != "application/json" || r.FormValue("desc") != "用户数据" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	filePath := filepath.Join(t.TempDir(), "data.json")
+	if err := os.WriteFile(filePath, []byte(`{"name":"张三"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	statusCode, _, err := PostFile(context.Background(), server.URL, "file", filePath, map[string]string{"desc": "用户数据"})
+	if err != nil {
+		t.Fatalf("上传失败: %v", err)
+	}
+	if statusCode != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusOK, statusCode)
+	}
+
+	// 文件不存在
+	if _, _, err := PostFile(context.Background(), server.URL, "file", filePath+".missing", nil); err == nil {
+		t.Fatal("文件不存在时应返回错误")
+	}
+}
@@ -0,0 +1,54 @@
+package httptool
+
+import (
+	"io"
+	"sync"
+)
+
+// maxPooledBodySize 超过这个大小的缓冲区不放回池中, 避免偶尔的大响应让池长期占用大量内存
+const maxPooledBodySize = 1 << 20
+
+var bodyPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 32*1024)
+		return &b
+	},
+}
+
+// WithBodyPool 把响应体读到 sync.Pool 复用的缓冲区中, 降低高吞吐场景下 io.ReadAll 分配内存带来的 GC 压力
+// 调用方用完响应体后必须调用 ReleaseBody 归还缓冲区; 归还之后不能再使用响应体(包括从中切出的子切片), 它随时可能被其他请求覆盖
+// 不调用 ReleaseBody 不会出错, 只是缓冲区交给 GC 回收, 没有复用的效果
+func WithBodyPool() Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.bodyPool = true
+		return
+	})
+}
+
+// ReleaseBody 归还 WithBodyPool 读取的响应体的缓冲区, 同一个响应体只能归还一次
+func ReleaseBody(b []byte) {
+	if b == nil || cap(b) > maxPooledBodySize {
+		return
+	}
+	b = b[:0]
+	bodyPool.Put(&b)
+}
+
+// readPooled 与 io.ReadAll 相同, 但是读到池中的缓冲区里
+func readPooled(r io.Reader) ([]byte, error) {
+	b := (*bodyPool.Get().(*[]byte))[:0]
+	for {
+		if len(b) == cap(b) {
+			b = append(b, 0)[:len(b)] // 缓冲区满了, 由 append 扩容
+		}
+		n, err := r.Read(b[len(b):cap(b)])
+		b = b[:len(b)+n]
+		if err == io.EOF {
+			return b, nil
+		}
+		if err != nil {
+			ReleaseBody(b)
+			return nil, err
+		}
+	}
+}
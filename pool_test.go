@@ -0,0 +1,60 @@
+package httptool
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestWithBodyPool 测试读到复用的缓冲区
+func TestWithBodyPool(t *testing.T) {
+	ResetDefaultClient()
+
+	want := strings.Repeat("0123456789", 10000) // 超过初始容量, 需要扩容
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(want))
+	}))
+	defer server.Close()
+
+	for i := 0; i < 3; i++ {
+		_, body, err := Get(context.Background(), server.URL, WithBodyPool())
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		if string(body) != want {
+			t.Fatalf("响应体不正确, 长度 %d", len(body))
+		}
+		ReleaseBody(body)
+	}
+}
+
+var benchmarkBody = bytes.Repeat([]byte("x"), 64*1024)
+
+// BenchmarkReadBody 对比直接读取和使用缓冲池读取响应体的内存分配
+func BenchmarkReadBody(b *testing.B) {
+	b.Run("ReadAll", func(b *testing.B) {
+		opts := defaultRequestOptions()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := opts.readBody(bytes.NewReader(benchmarkBody)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("BodyPool", func(b *testing.B) {
+		opts := defaultRequestOptions()
+		WithBodyPool().apply(opts)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			body, err := opts.readBody(bytes.NewReader(benchmarkBody))
+			if err != nil {
+				b.Fatal(err)
+			}
+			ReleaseBody(body)
+		}
+	})
+}
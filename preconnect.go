@@ -0,0 +1,262 @@
+package httptool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrPreconnectUnsupported 客户端的 Transport 不是 httptool 创建的(如 SetHttpClient、WithClient 传入的客户端), 无法预先拨号时返回
+// 这种情况可以用 WithPreconnectProbe 改为发送请求建立连接
+var ErrPreconnectUnsupported = errors.New("preconnect requires a transport created by httptool")
+
+// Preconnect 提前建立到 url 所在 host 的 TCP 连接(DNS 解析、TCP 握手), 之后对同一个 host 的第一个请求直接使用这条连接
+// 适合构造请求体很耗时的场景, 在另一个 goroutine 中调用, 让建立连接和准备请求体同时进行:
+//
+//	go httptool.Preconnect(ctx, url)
+//	data := buildLargeBody()
+//	httptool.Post(ctx, url, data)
+//
+// 只拨号不发送请求, 服务端收不到任何请求; HTTPS 的 TLS 握手仍在第一个请求时进行. 预先建立的连接在 Transport 的
+// IdleConnTimeout(默认 90 秒)内没有被使用会被关闭; 使用前会检查连接, 已被服务端关闭时重新拨号. 只支持 httptool 创建的 Transport(全局默认客户端、派生 Transport 和会话),
+// 其他 Transport 返回 ErrPreconnectUnsupported, 除非设置了 WithPreconnectProbe
+// options 中影响连接池的选项(如 WithResponseTimeout、WithConnectTimeout, 以及会话的客户端)需要与之后的请求一致, 否则预先建立的连接在另一个连接池中;
+// 同一组 options 可以直接传给之后的请求, WithPreconnectProbe 只对 Preconnect 生效, 其他请求函数会忽略它
+func Preconnect(ctx context.Context, url string, options ...Option) error {
+	opts := defaultRequestOptions()
+	opts.ctx = ctx
+	for _, opt := range options {
+		if err := opt.apply(opts); err != nil {
+			return err
+		}
+	}
+	ctx, cancel := opts.timeoutContext()
+	defer cancel()
+
+	client, err := opts.httpClient()
+	if err != nil {
+		return err
+	}
+	if opts.preconnectProbe != "" {
+		return probeConnect(ctx, client, opts.preconnectProbe, url)
+	}
+	tr, _ := client.Transport.(*http.Transport)
+	w, ok := warmDialers.Load(tr)
+	if tr == nil || !ok {
+		return ErrPreconnectUnsupported
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	addr, err := dialAddr(tr, req)
+	if err != nil {
+		return err
+	}
+	return w.(*warmDialer).warm(ctx, "tcp", addr)
+}
+
+// WithPreconnectProbe Preconnect 改为发送一个 method 请求来建立连接, 服务端会收到这个请求(可能经过鉴权、WAF、CORS 等处理), 返回任何状态码都视为成功
+// 用于 SetHttpClient、WithClient 传入的自定义 Transport, 这时无法预先拨号; 与拨号相比还能提前完成 TLS 握手
+// 只对 Preconnect 生效, Request、Get 等函数会忽略这个选项, 因此预连接和之后的请求可以共用同一组 options
+func WithPreconnectProbe(method string) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.preconnectProbe = method
+		return
+	})
+}
+
+// probeConnect 发送一个请求建立连接, 读完响应体连接才会放回连接池
+func probeConnect(ctx context.Context, client *http.Client, method string, url string) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// dialAddr 按 Transport 的规则计算请求要拨号的地址, 设置了代理时为代理的地址
+func dialAddr(tr *http.Transport, req *http.Request) (string, error) {
+	target := req.URL
+	if tr.Proxy != nil {
+		proxyURL, err := tr.Proxy(req)
+		if err != nil {
+			return "", err
+		}
+		if proxyURL != nil {
+			target = proxyURL
+		}
+	}
+	return canonicalAddr(target), nil
+}
+
+// canonicalAddr 返回 host:port, 没有端口时按 scheme 补上默认端口
+func canonicalAddr(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		switch strings.ToLower(u.Scheme) {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// warmDialers httptool 创建的 Transport 对应的 warmDialer, Preconnect 通过它预先拨号
+var warmDialers sync.Map // map[*http.Transport]*warmDialer
+
+type dialFunc = func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// warmDialer 包装 Transport 的 DialContext, Preconnect 预先建立的连接交给之后对同一地址的第一次拨号
+type warmDialer struct {
+	dial        dialFunc // 原来的 DialContext
+	idleTimeout time.Duration
+
+	mu    sync.Mutex
+	conns map[string][]*warmConn // key 为 network 和地址
+}
+
+type warmConn struct {
+	net.Conn
+	timer *time.Timer // 一直没有被使用时关闭连接
+}
+
+// enableWarmDial 在 httptool 新创建的 tr 上安装 warmDialer, 需要在包装请求计数等 DialContext 之前调用, 这些包装对预先建立的连接同样生效
+func enableWarmDial(tr *http.Transport) {
+	dial := tr.DialContext
+	if dial == nil {
+		dial = transportConfig{}.dialer().DialContext
+	}
+	idleTimeout := tr.IdleConnTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = 90 * time.Second
+	}
+	w := &warmDialer{dial: dial, idleTimeout: idleTimeout, conns: map[string][]*warmConn{}}
+	tr.DialContext = w.DialContext
+	warmDialers.Store(tr, w)
+}
+
+// cloneTransport 克隆 base, 克隆出的 Transport 使用 base 原来的 DialContext, 不会拿到 base 预先建立的连接
+func cloneTransport(base *http.Transport) *http.Transport {
+	tr := base.Clone()
+	if w, ok := warmDialers.Load(base); ok {
+		tr.DialContext = w.(*warmDialer).dial
+	}
+	return tr
+}
+
+// forgetTransport tr 不会再被使用(派生 Transport 被移除、会话关闭)时移除它的 warmDialer, 并关闭还没有被使用的预先建立的连接
+func forgetTransport(tr *http.Transport) {
+	if w, ok := warmDialers.LoadAndDelete(tr); ok {
+		w.(*warmDialer).closeAll()
+	}
+}
+
+// closeWarmConns 关闭 tr 还没有被使用的预先建立的连接, 但保留 warmDialer:
+// 被 SetHttpClient 换下的客户端可能之后又被设置回来(如 httptooltest.Install 结束时恢复原客户端), 那时仍然可以预连接
+func closeWarmConns(tr *http.Transport) {
+	if w, ok := warmDialers.Load(tr); ok {
+		w.(*warmDialer).closeAll()
+	}
+}
+
+// warm 预先拨号, 连接保存到之后对同一地址的拨号
+func (w *warmDialer) warm(ctx context.Context, network, addr string) error {
+	conn, err := w.dial(ctx, network, addr)
+	if err != nil {
+		return err
+	}
+	key := network + " " + addr
+	wc := &warmConn{Conn: conn}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	wc.timer = time.AfterFunc(w.idleTimeout, func() {
+		if w.take(key, wc) {
+			wc.Conn.Close()
+		}
+	})
+	w.conns[key] = append(w.conns[key], wc)
+	return nil
+}
+
+// DialContext 有预先建立的连接时直接使用, 否则正常拨号
+func (w *warmDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	key := network + " " + addr
+	w.mu.Lock()
+	var wc *warmConn
+	if conns := w.conns[key]; len(conns) > 0 {
+		wc = conns[0]
+		w.conns[key] = conns[1:]
+		if len(w.conns[key]) == 0 {
+			delete(w.conns, key)
+		}
+	}
+	w.mu.Unlock()
+	if wc != nil {
+		if wc.timer.Stop() && wc.alive() {
+			return wc.Conn, nil
+		}
+		wc.Conn.Close() // 已经超过空闲时间或已被服务端关闭, 重新拨号
+	}
+	return w.dial(ctx, network, addr)
+}
+
+// alive 检查预先建立的连接是否还能使用. 服务端可能已经关闭了一直没有请求的连接(如 ReadHeaderTimeout 到期),
+// 而 Transport 把拨号得到的连接当作新连接, 在它上面失败的请求不会重试, 所以交出去之前先检查
+// 用很短的读超时读一次: 超时说明连接正常且没有数据; 读到 EOF、错误或服务端主动发来的数据(如 408 响应)都不能再使用
+func (wc *warmConn) alive() bool {
+	if err := wc.Conn.SetReadDeadline(time.Now().Add(time.Millisecond)); err != nil {
+		return false
+	}
+	var buf [1]byte
+	_, err := wc.Conn.Read(buf[:])
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		return false
+	}
+	return wc.Conn.SetReadDeadline(time.Time{}) == nil
+}
+
+// take 从保存的连接中移除 wc, 返回 wc 是否还在(没有被拨号拿走)
+func (w *warmDialer) take(key string, wc *warmConn) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	conns := w.conns[key]
+	for i, c := range conns {
+		if c == wc {
+			w.conns[key] = append(conns[:i:i], conns[i+1:]...)
+			if len(w.conns[key]) == 0 {
+				delete(w.conns, key)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func (w *warmDialer) closeAll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, conns := range w.conns {
+		for _, wc := range conns {
+			wc.timer.Stop()
+			wc.Conn.Close()
+		}
+	}
+	w.conns = map[string][]*warmConn{}
+}
@@ -0,0 +1,170 @@
+package httptool
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestPreconnect 测试预先拨号的连接被之后的请求使用, 服务端收不到额外的请求
+func TestPreconnect(t *testing.T) {
+	ResetDefaultClient()
+	defer ResetDefaultClient()
+
+	var conns, requests atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte("ok"))
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	ctx := context.Background()
+	for _, options := range [][]Option{nil, {WithConnectTimeout(time.Second)}} {
+		conns.Store(0)
+		requests.Store(0)
+		if err := Preconnect(ctx, server.URL, options...); err != nil {
+			t.Fatalf("预连接失败: %v", err)
+		}
+		// 服务端接受连接是异步的, 等它记录下预先建立的连接
+		for deadline := time.Now().Add(time.Second); conns.Load() == 0 && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+		if conns.Load() != 1 || requests.Load() != 0 {
+			t.Fatalf("预连接应只建立连接不发送请求, 得到连接数 %d 请求数 %d", conns.Load(), requests.Load())
+		}
+		if _, body, err := Post(ctx, server.URL, []byte("{}"), options...); err != nil || string(body) != "ok" {
+			t.Fatalf("请求失败: %q %v", string(body), err)
+		}
+		if conns.Load() != 1 || requests.Load() != 1 {
+			t.Fatalf("期望使用预先建立的连接, 得到连接数 %d 请求数 %d", conns.Load(), requests.Load())
+		}
+	}
+
+	if err := Preconnect(ctx, "http://127.0.0.1:1"); err == nil {
+		t.Fatal("连接失败时期望返回错误")
+	}
+
+	// 临时换成其他客户端再恢复(如 httptooltest.Install), 恢复后仍然可以预连接
+	previous := GetHttpClient()
+	SetHttpClient(&http.Client{})
+	SetHttpClient(previous)
+	if err := Preconnect(ctx, server.URL); err != nil {
+		t.Fatalf("恢复原客户端后预连接失败: %v", err)
+	}
+
+	// WithPreconnectProbe 只对 Preconnect 生效, 普通请求忽略它
+	requests.Store(0)
+	if _, body, err := Get(ctx, server.URL, WithPreconnectProbe(http.MethodHead)); err != nil || string(body) != "ok" || requests.Load() != 1 {
+		t.Fatalf("普通请求应忽略 WithPreconnectProbe, 得到 %q 请求数 %d %v", string(body), requests.Load(), err)
+	}
+}
+
+// TestPreconnectServerClosed 测试预先建立的连接被服务端关闭后, 请求重新拨号而不是失败
+func TestPreconnectServerClosed(t *testing.T) {
+	ResetDefaultClient()
+	defer ResetDefaultClient()
+
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	// 连接建立后 100 毫秒内没有收到请求头, 服务端关闭连接
+	server.Config.ReadHeaderTimeout = 100 * time.Millisecond
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	ctx := context.Background()
+	if err := Preconnect(ctx, server.URL); err != nil {
+		t.Fatalf("预连接失败: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if _, body, err := Get(ctx, server.URL); err != nil || string(body) != "ok" {
+		t.Fatalf("预先建立的连接被关闭后请求应重新拨号, 得到 %q %v", string(body), err)
+	}
+	if conns.Load() != 2 {
+		t.Fatalf("期望重新拨号建立第二条连接, 得到连接数 %d", conns.Load())
+	}
+}
+
+// TestPreconnectProbe 测试自定义客户端不能预先拨号, 可以改为发送请求建立连接
+func TestPreconnectProbe(t *testing.T) {
+	ResetDefaultClient()
+	defer ResetDefaultClient()
+
+	var conns atomic.Int32
+	var probeMethod atomic.Value
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/probe" {
+			probeMethod.Store(r.Method)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+	SetHttpClient(server.Client())
+
+	ctx := context.Background()
+	if err := Preconnect(ctx, server.URL); !errors.Is(err, ErrPreconnectUnsupported) {
+		t.Fatalf("自定义 Transport 期望 ErrPreconnectUnsupported, 得到 %v", err)
+	}
+	if err := Preconnect(ctx, server.URL+"/probe", WithPreconnectProbe(http.MethodHead)); err != nil {
+		t.Fatalf("预连接失败: %v", err)
+	}
+	if method, _ := probeMethod.Load().(string); method != http.MethodHead {
+		t.Fatalf("期望服务端收到 HEAD 请求, 得到 %q", method)
+	}
+	var stats Stats
+	if _, _, err := Get(ctx, server.URL, WithStats(&stats)); err != nil || !stats.ConnReused || conns.Load() != 1 {
+		t.Fatalf("期望复用预连接建立的连接, 得到复用 %v 连接数 %d %v", stats.ConnReused, conns.Load(), err)
+	}
+}
+
+// BenchmarkPreconnect 对比首次请求在有无预连接时的耗时, 预连接在计时之外完成, 对应建立连接与准备请求体完全重叠的情况
+func BenchmarkPreconnect(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	ctx := context.Background()
+
+	for _, preconnect := range []bool{false, true} {
+		name := "cold"
+		if preconnect {
+			name = "preconnected"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				ResetDefaultClient() // 每次都从空的连接池开始
+				if preconnect {
+					Preconnect(ctx, server.URL)
+				}
+				b.StartTimer()
+				Get(ctx, server.URL, WithLogger(Default.LogMode(Silent)))
+			}
+			b.StopTimer()
+			ResetDefaultClient()
+		})
+	}
+}
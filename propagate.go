@@ -0,0 +1,27 @@
+package httptool
+
+import (
+	"context"
+	"net/http"
+)
+
+type incomingHeaderKey struct{}
+
+// ContextWithIncomingHeader 把收到的请求的请求头保存到上下文中, 之后发出的请求可以通过 WithPropagateHeaders 透传其中的请求头
+// 一般在网关或服务的入口中间件中调用: ctx := httptool.ContextWithIncomingHeader(r.Context(), r.Header)
+func ContextWithIncomingHeader(ctx context.Context, header http.Header) context.Context {
+	return context.WithValue(ctx, incomingHeaderKey{}, header.Clone())
+}
+
+// WithPropagateHeaders 把 ContextWithIncomingHeader 保存的请求头中的 keys 复制到发出的请求上, 如 X-Request-ID、traceparent、X-Tenant-ID
+// 收到的请求中没有的请求头会被跳过; 上下文中没有保存请求头时不做任何事
+func WithPropagateHeaders(ctx context.Context, keys ...string) Option {
+	incoming, _ := ctx.Value(incomingHeaderKey{}).(http.Header)
+	propagated := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if value := incoming.Get(key); value != "" {
+			propagated[key] = value
+		}
+	}
+	return WithHeaders(propagated)
+}
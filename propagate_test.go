@@ -0,0 +1,40 @@
+package httptool
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestWithPropagateHeaders 测试透传收到的请求的请求头
+func TestWithPropagateHeaders(t *testing.T) {
+	ResetDefaultClient()
+
+	var downstream http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstream = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	incoming := http.Header{}
+	incoming.Set("X-Request-Id", "req-1")
+	incoming.Set("Cookie", "session=secret")
+	ctx := ContextWithIncomingHeader(context.Background(), incoming)
+
+	if _, _, err := Get(ctx, server.URL, WithPropagateHeaders(ctx, "X-Request-ID", "Traceparent")); err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if downstream.Get("X-Request-Id") != "req-1" {
+		t.Fatalf("期望透传 X-Request-Id, 得到 %v", downstream)
+	}
+	if downstream.Get("Cookie") != "" || downstream.Get("Traceparent") != "" {
+		t.Fatalf("只应透传指定且存在的请求头, 得到 %v", downstream)
+	}
+
+	// 上下文中没有保存请求头时不做任何事
+	if _, _, err := Get(context.Background(), server.URL, WithPropagateHeaders(context.Background(), "X-Request-ID")); err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+}
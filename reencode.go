@@ -0,0 +1,181 @@
+package httptool
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ErrUnsupportedReencode WithBodyReencode 不支持指定的格式转换时返回
+var ErrUnsupportedReencode = errors.New("unsupported body re-encode")
+
+// WithBodyReencode 把响应体从 from 格式转换为 to 格式后返回, 用于接收一种格式、返回另一种格式的转发代理
+// 内置支持 "json" 和 "xml" 之间的转换; 转换在读取响应体之后立即进行, 之后的状态码处理、校验和提取看到的都是转换后的响应体
+// XML 转 JSON 时根元素作为最外层的key, 属性的key加 "@" 前缀, 同名的子元素合并为数组, 元素同时有文本和子元素时文本的key为 "#text";
+// JSON 转 XML 按相同的约定反向转换, 最外层不是只有一个key的对象时包在 <root> 元素中
+func WithBodyReencode(from string, to string) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		from, to = strings.ToLower(from), strings.ToLower(to)
+		switch {
+		case from == "xml" && to == "json":
+			opts.bodyReencode = xmlToJSON
+		case from == "json" && to == "xml":
+			opts.bodyReencode = jsonToXML
+		default:
+			return fmt.Errorf("%w: %q to %q (supported: json to xml, xml to json)", ErrUnsupportedReencode, from, to)
+		}
+		return
+	})
+}
+
+// xmlNode 解析 XML 时的元素
+type xmlNode struct {
+	name     string
+	attrs    []xml.Attr
+	children []*xmlNode
+	text     strings.Builder
+}
+
+// xmlToJSON 把 XML 文档转换为 JSON
+func xmlToJSON(data []byte) ([]byte, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var root *xmlNode
+	var stack []*xmlNode
+	for {
+		token, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decode xml: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name.Local, attrs: t.Attr}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else if root == nil {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("decode xml: no root element")
+	}
+	return json.Marshal(map[string]interface{}{root.name: root.value()})
+}
+
+// value 元素对应的 JSON 值
+func (n *xmlNode) value() interface{} {
+	text := strings.TrimSpace(n.text.String())
+	if len(n.attrs) == 0 && len(n.children) == 0 {
+		return text
+	}
+	object := map[string]interface{}{}
+	for _, attr := range n.attrs {
+		object["@"+attr.Name.Local] = attr.Value
+	}
+	for _, child := range n.children {
+		value := child.value()
+		switch existing := object[child.name].(type) {
+		case nil:
+			object[child.name] = value
+		case []interface{}:
+			object[child.name] = append(existing, value)
+		default:
+			object[child.name] = []interface{}{existing, value}
+		}
+	}
+	if text != "" {
+		object["#text"] = text
+	}
+	return object
+}
+
+// jsonToXML 把 JSON 文档转换为 XML
+func jsonToXML(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // 保留数字的原始写法
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("decode json: %w", err)
+	}
+	name := "root"
+	if object, ok := value.(map[string]interface{}); ok && len(object) == 1 {
+		for k, v := range object {
+			name, value = k, v
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	if err := encodeXMLElement(enc, name, value); err != nil {
+		return nil, fmt.Errorf("encode xml: %w", err)
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, fmt.Errorf("encode xml: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeXMLElement 把 JSON 值编码为名为 name 的元素, 数组编码为多个同名元素
+func encodeXMLElement(enc *xml.Encoder, name string, value interface{}) error {
+	if array, ok := value.([]interface{}); ok {
+		for _, item := range array {
+			if err := encodeXMLElement(enc, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	object, isObject := value.(map[string]interface{})
+	keys := make([]string, 0, len(object))
+	for k := range object {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys) // 输出稳定的顺序
+	for _, k := range keys {
+		if strings.HasPrefix(k, "@") {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: k[1:]}, Value: fmt.Sprint(object[k])})
+		}
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	switch {
+	case isObject:
+		for _, k := range keys {
+			switch {
+			case strings.HasPrefix(k, "@"):
+			case k == "#text":
+				if err := enc.EncodeToken(xml.CharData(fmt.Sprint(object[k]))); err != nil {
+					return err
+				}
+			default:
+				if err := encodeXMLElement(enc, k, object[k]); err != nil {
+					return err
+				}
+			}
+		}
+	case value != nil:
+		if err := enc.EncodeToken(xml.CharData(fmt.Sprint(value))); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
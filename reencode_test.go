@@ -0,0 +1,50 @@
+package httptool
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestWithBodyReencode 测试响应体格式转换
+func TestWithBodyReencode(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		if r.URL.Query().Get("format") == "json" {
+			w.Write([]byte(`{"order":{"@id":"7","item":["a","b"],"total":12.5}}`))
+			return
+		}
+		w.Write([]byte(`<order id="7"><item>a</item><item>b</item><total>12.5</total></order>`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		url      string
+		from, to string
+		want     string
+	}{
+		{"xml 转 json", server.URL, "xml", "json", `{"order":{"@id":"7","item":["a","b"],"total":"12.5"}}`},
+		{"json 转 xml", server.URL + "?format=json", "json", "xml", `<order id="7"><item>a</item><item>b</item><total>12.5</total></order>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, body, err := Get(ctx, tt.url, WithBodyReencode(tt.from, tt.to))
+			if err != nil {
+				t.Fatalf("请求失败: %v", err)
+			}
+			if string(body) != tt.want {
+				t.Fatalf("期望 %s, 得到 %s", tt.want, string(body))
+			}
+		})
+	}
+
+	if _, _, err := Get(ctx, server.URL, WithBodyReencode("xml", "yaml")); !errors.Is(err, ErrUnsupportedReencode) {
+		t.Fatalf("期望 ErrUnsupportedReencode, 得到 %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package httptool
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// ErrInteractionNotFound WithReplay 的录制文件中没有与请求匹配的记录时返回
+var ErrInteractionNotFound = errors.New("no recorded interaction matches the request")
+
+// Interaction 录制文件中的一次请求和响应
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest 录制的请求, 敏感请求头(如 Authorization)的值会被替换为 [REDACTED]
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// RecordedResponse 录制的响应
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// cassette 一个录制文件, 同一个进程内相同路径共用一个
+type cassette struct {
+	mu           sync.Mutex
+	path         string
+	interactions []Interaction
+	used         []bool // 回放时已经用过的记录
+	recording    bool   // 本进程内已经开始录制, 录制的内容已在内存中
+	loaded       bool
+}
+
+var cassettes sync.Map // path -> *cassette
+
+func getCassette(path string) *cassette {
+	c, _ := cassettes.LoadOrStore(path, &cassette{path: path})
+	return c.(*cassette)
+}
+
+// WithRecord 把请求和响应录制到 path 指定的 JSON 文件中, 之后可以用 WithReplay 回放, 用于让集成测试在没有网络时也能运行
+// 同一个进程内第一次录制时清空文件, 之后的请求追加到文件末尾; 敏感请求头的值不会被录制
+func WithRecord(path string) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.cassette, opts.replay = getCassette(path), false
+		return
+	})
+}
+
+// WithReplay 从 WithRecord 录制的文件中回放响应, 不发出真实的请求
+// 按方法、URL 和请求体匹配记录, 有多条匹配时按录制的顺序依次返回, 用完后一直返回最后一条; 没有匹配时返回 ErrInteractionNotFound
+func WithReplay(path string) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.cassette, opts.replay = getCassette(path), true
+		return
+	})
+}
+
+// cassetteClient 设置了 WithRecord 或 WithReplay 时返回经过录制文件的客户端
+func (opts *requestOption) cassetteClient(c *http.Client) *http.Client {
+	if opts.cassette == nil {
+		return c
+	}
+	base := c.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	wrapped := *c
+	wrapped.Transport = &cassetteTransport{base: base, cassette: opts.cassette, replay: opts.replay}
+	return &wrapped
+}
+
+type cassetteTransport struct {
+	base     http.RoundTripper
+	cassette *cassette
+	replay   bool
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if t.replay {
+		return t.cassette.replay(req, reqBody)
+	}
+
+	sent := req.Clone(req.Context())
+	sent.Body = io.NopCloser(bytes.NewReader(reqBody))
+	resp, err := t.base.RoundTrip(sent)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	header := req.Header.Clone()
+	for key := range header {
+		if sensitiveHeaders[http.CanonicalHeaderKey(key)] { // WithRawHeader 设置的请求头保留了原来的大小写
+			header[key] = []string{"[REDACTED]"}
+		}
+	}
+	err = t.cassette.record(Interaction{
+		Request:  RecordedRequest{Method: req.Method, URL: req.URL.String(), Header: header, Body: reqBody},
+		Response: RecordedResponse{StatusCode: resp.StatusCode, Header: resp.Header.Clone(), Body: respBody},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// record 追加一条记录并写回文件
+func (c *cassette) record(interaction Interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.recording { // 本进程内第一次录制, 清掉之前的内容
+		c.interactions, c.used, c.recording = nil, nil, true
+	}
+	c.interactions = append(c.interactions, interaction)
+	c.used = append(c.used, false)
+	data, err := json.MarshalIndent(c.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0o644)
+}
+
+// replay 返回与请求匹配的记录
+func (c *cassette) replay(req *http.Request, reqBody []byte) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.recording && !c.loaded {
+		data, err := os.ReadFile(c.path)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(data, &c.interactions); err != nil {
+			return nil, fmt.Errorf("invalid cassette %s: %w", c.path, err)
+		}
+		c.used, c.loaded = make([]bool, len(c.interactions)), true
+	}
+
+	match := -1
+	for i, interaction := range c.interactions {
+		r := interaction.Request
+		if r.Method != req.Method || r.URL != req.URL.String() || !bytes.Equal(r.Body, reqBody) {
+			continue
+		}
+		match = i
+		if !c.used[i] {
+			break
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, req.Method, req.URL)
+	}
+	c.used[match] = true
+
+	recorded := c.interactions[match].Response
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
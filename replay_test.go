@@ -0,0 +1,91 @@
+package httptool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRecordAndReplay 测试录制和回放
+func TestRecordAndReplay(t *testing.T) {
+	ResetDefaultClient()
+
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Count", string(rune('0'+count)))
+		w.Write([]byte(r.Method + " " + string(body)))
+	}))
+	url := server.URL
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	ctx := context.Background()
+	headers := WithHeaders(map[string]string{"Authorization": "Bearer secret"})
+	for _, body := range []string{"a", "b", "b"} {
+		if _, _, err := Request(http.MethodPost, url, WithContext(ctx), WithData([]byte(body)), WithRecord(path), headers); err != nil {
+			t.Fatalf("录制失败: %v", err)
+		}
+	}
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取录制文件失败: %v", err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Fatal("录制文件中不应包含敏感请求头的值")
+	}
+
+	// 回放时服务端已经关闭, 响应全部来自录制文件
+	cassettes.Delete(path) // 模拟新的进程从文件加载
+	replay := func(body string) (int, http.Header, string, error) {
+		statusCode, header, respBody, err := RequestWithResponse(http.MethodPost, url, WithData([]byte(body)), WithReplay(path))
+		return statusCode, header, string(respBody), err
+	}
+	for _, tt := range []struct {
+		body, count string
+	}{{"b", "2"}, {"a", "1"}, {"b", "3"}, {"b", "3"}} {
+		statusCode, header, respBody, err := replay(tt.body)
+		if err != nil {
+			t.Fatalf("回放失败: %v", err)
+		}
+		if statusCode != http.StatusOK || respBody != "POST "+tt.body || header.Get("X-Count") != tt.count {
+			t.Fatalf("回放 %q 期望第 %s 条记录, 得到 %d %q %v", tt.body, tt.count, statusCode, respBody, header)
+		}
+	}
+
+	if _, _, _, err := replay("c"); !errors.Is(err, ErrInteractionNotFound) {
+		t.Fatalf("没有匹配的记录时期望 ErrInteractionNotFound, 得到 %v", err)
+	}
+}
+
+// TestRecordRawHeaderRedacted 测试 WithRawHeader 设置的小写敏感请求头同样不写入录制文件
+func TestRecordRawHeaderRedacted(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	_, _, err := Get(context.Background(), server.URL, WithRecord(path),
+		WithRawHeader("authorization", "Bearer raw-secret"), WithRawHeader("x-api-key", "key-secret"))
+	if err != nil {
+		t.Fatalf("录制失败: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取录制文件失败: %v", err)
+	}
+	if strings.Contains(string(data), "secret") || !strings.Contains(string(data), "[REDACTED]") {
+		t.Fatalf("录制文件中不应包含敏感请求头的值, 得到 %s", data)
+	}
+}
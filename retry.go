@@ -0,0 +1,134 @@
+package httptool
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// defaultRetryStatus 默认重试的状态码, 一般是网关或服务端暂时不可用
+var defaultRetryStatus = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// WithRetry 请求失败时重试, 最多发送 maxAttempts 次(包括第一次)
+// 连接错误、读取响应体时连接中断和 WithRetryStatus 指定的状态码(默认 502/503/504)会重试, 第 n 次重试前等待 baseDelay*2^(n-1), 最多 1 分钟(可通过 WithMaxRetryDelay 修改)
+// 每次重试都会重新生成请求体; 所有重试共用 WithTimeout 的超时, 上下文结束或剩余时间不够等待时不再重试
+// 非幂等的方法(如 POST)只有带了 Idempotency-Key 请求头(如设置了 WithIdempotencyKey)才会重试, 避免重复提交
+// 响应体已经交给流式处理(StreamNDJSON、RequestStream 等)或 WithBodyObserver 后不再重试, 包括 WithStatusHandler 处理的状态码
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		if maxAttempts < 1 || baseDelay < 0 {
+			return fmt.Errorf("retry requires at least 1 attempt and a non-negative delay, got %d and %s", maxAttempts, baseDelay)
+		}
+		opts.retryAttempts, opts.retryBaseDelay = maxAttempts, baseDelay
+		return
+	})
+}
+
+// WithRetryJitter 重试的等待时间加上随机抖动, 在 [delay/2, delay) 之间随机取值, 避免大量客户端同时重试
+func WithRetryJitter() Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.retryJitter = true
+		return
+	})
+}
+
+// WithMaxRetryDelay 设置每次重试等待时间的上限, 替换默认的 1 分钟, 指数退避的结果(包括 baseDelay 本身)超过 d 时按 d 等待
+// 等待时间同样受 WithTimeout 限制: 剩余时间不够等待时不再重试, 直接返回最后一次的结果
+func WithMaxRetryDelay(d time.Duration) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		if d <= 0 {
+			return fmt.Errorf("max retry delay must be positive, got %s", d)
+		}
+		opts.retryMaxDelay = d
+		return
+	})
+}
+
+// WithRetryStatus 设置触发重试的状态码, 替换默认的 502/503/504, 如有的接口用 429 表示可以重试
+// 非幂等的方法仍然需要 Idempotency-Key 请求头才会重试
+func WithRetryStatus(codes ...int) Option {
+	codes = slices.Clone(codes)
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.retryStatus = codes
+		return
+	})
+}
+
+// sendWithRetry 设置了 WithRetry 时按退避策略重试, 否则只发送一次
+func (opts *requestOption) sendWithRetry(method string, url string) (httpStatusCode int, header http.Header, respBody []byte, err error) {
+	attempt := 1
+	defer func() {
+		if opts.stats != nil {
+			opts.stats.Attempts = attempt
+		}
+	}()
+	for ; ; attempt++ {
+		httpStatusCode, header, respBody, err = opts.sendHedged(method, url)
+		if attempt >= opts.retryAttempts || !opts.shouldRetry(method, httpStatusCode, err) {
+			return
+		}
+		delay := opts.retryDelay(attempt)
+		if deadline, ok := opts.ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return // 等不到下一次重试就会超时, 直接返回这次的结果
+		}
+		opts.logger.Info(opts.ctx, "HTTP_REQUEST_RETRY_LOG", opts.withLoggerFields("method", method, "url", url, "attempt", attempt, "status", httpStatusCode, "err", err, "delay", delay)...)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-opts.ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// shouldRetry 本次请求的结果是否可以重试
+func (opts *requestOption) shouldRetry(method string, httpStatusCode int, err error) bool {
+	// 响应体已经交给流式处理或观察者(包括 WithStatusHandler 处理的状态码), 重新请求会重复交出数据, 不重试
+	if err == nil || opts.ctx.Err() != nil || opts.bodyNotReplayable || opts.bodyHandedOut {
+		return false
+	}
+	if !isIdempotent(method) && headerValue(opts.headers, "Idempotency-Key") == "" {
+		return false
+	}
+	// 读取响应体时连接被断开, 对幂等的请求重新请求是安全的
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	if httpStatusCode != 0 {
+		retryStatus := opts.retryStatus
+		if retryStatus == nil {
+			retryStatus = defaultRetryStatus
+		}
+		return slices.Contains(retryStatus, httpStatusCode)
+	}
+	// 没有拿到响应: 只重试网络错误, 请求地址不合法之类的错误重试也没用
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF)
+}
+
+// maxRetryDelay 没有设置 WithMaxRetryDelay 时指数退避的等待时间上限, 避免重试次数很多时等待时间过长或溢出
+const maxRetryDelay = time.Minute
+
+// retryDelay 第 attempt 次请求失败后的等待时间, 翻倍到上限为止
+// 上限为 WithMaxRetryDelay 的设置; 没有设置时为 maxRetryDelay, 这时 baseDelay 本身更大则使用 baseDelay
+func (opts *requestOption) retryDelay(attempt int) time.Duration {
+	limit := max(maxRetryDelay, opts.retryBaseDelay)
+	if opts.retryMaxDelay > 0 {
+		limit = opts.retryMaxDelay
+	}
+	delay := opts.retryBaseDelay
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	delay = min(delay, limit)
+	if opts.retryJitter && delay > 1 {
+		delay = delay/2 + rand.N(delay/2) // [delay/2, delay)
+	}
+	return delay
+}
@@ -0,0 +1,221 @@
+package httptool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestWithRetry 测试失败重试
+func TestWithRetry(t *testing.T) {
+	ResetDefaultClient()
+
+	var requests atomic.Int32
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if requests.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	reset := func() {
+		requests.Store(0)
+		bodies = nil
+	}
+
+	t.Run("重试后成功", func(t *testing.T) {
+		reset()
+		var stats Stats
+		_, body, err := Get(ctx, server.URL, WithRetry(3, 10*time.Millisecond), WithStats(&stats))
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		if string(body) != "ok" || stats.Attempts != 3 {
+			t.Fatalf("期望第 3 次成功, 得到 %q 共 %d 次", string(body), stats.Attempts)
+		}
+	})
+
+	t.Run("次数用完", func(t *testing.T) {
+		reset()
+		if _, _, err := Get(ctx, server.URL, WithRetry(2, 10*time.Millisecond)); err == nil {
+			t.Fatal("重试次数用完时期望返回错误")
+		}
+		if n := requests.Load(); n != 2 {
+			t.Fatalf("期望发送 2 次, 实际 %d 次", n)
+		}
+	})
+
+	t.Run("状态码不重试", func(t *testing.T) {
+		reset()
+		if _, _, err := Get(ctx, server.URL, WithRetry(3, 10*time.Millisecond), WithRetryStatus(http.StatusTooManyRequests)); err == nil {
+			t.Fatal("期望返回错误")
+		}
+		if n := requests.Load(); n != 1 {
+			t.Fatalf("503 不在重试状态码中, 期望只发送 1 次, 实际 %d 次", n)
+		}
+	})
+
+	t.Run("POST 不重试", func(t *testing.T) {
+		reset()
+		if _, _, err := Post(ctx, server.URL, []byte(`{"a":"b"}`), WithRetry(3, 10*time.Millisecond)); err == nil {
+			t.Fatal("期望返回错误")
+		}
+		if n := requests.Load(); n != 1 {
+			t.Fatalf("没有幂等键的 POST 期望只发送 1 次, 实际 %d 次", n)
+		}
+	})
+
+	t.Run("自定义状态码的 POST 仍需幂等键", func(t *testing.T) {
+		reset()
+		if _, _, err := Post(ctx, server.URL, []byte(`{"a":"b"}`), WithRetry(3, 10*time.Millisecond), WithRetryStatus(http.StatusServiceUnavailable)); err == nil {
+			t.Fatal("期望返回错误")
+		}
+		if n := requests.Load(); n != 1 {
+			t.Fatalf("没有幂等键的 POST 即使状态码可以重试也期望只发送 1 次, 实际 %d 次", n)
+		}
+	})
+
+	t.Run("POST 带幂等键重试", func(t *testing.T) {
+		reset()
+		_, body, err := Post(ctx, server.URL, []byte(`{"a":"b"}`), WithRetry(3, 10*time.Millisecond), WithIdempotencyKey("op"))
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		if string(body) != "ok" || len(bodies) != 3 {
+			t.Fatalf("期望第 3 次成功, 得到 %q 共 %d 次", string(body), len(bodies))
+		}
+		for _, b := range bodies {
+			if b != `{"a":"b"}` {
+				t.Fatalf("每次重试的请求体应该相同, 得到 %q", b)
+			}
+		}
+	})
+
+	t.Run("超时不再等待", func(t *testing.T) {
+		reset()
+		start := time.Now()
+		if _, _, err := Get(ctx, server.URL, WithRetry(3, time.Second), WithTimeout(200*time.Millisecond)); err == nil {
+			t.Fatal("期望返回错误")
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Fatalf("剩余时间不够等待时应直接返回, 耗时 %s", elapsed)
+		}
+		if n := requests.Load(); n != 1 {
+			t.Fatalf("期望只发送 1 次, 实际 %d 次", n)
+		}
+	})
+
+	t.Run("参数不合法", func(t *testing.T) {
+		if _, _, err := Get(ctx, server.URL, WithRetry(0, time.Second)); err == nil {
+			t.Fatal("期望返回错误")
+		}
+	})
+}
+
+// TestRetryDelay 测试重试的等待时间
+func TestRetryDelay(t *testing.T) {
+	opts := defaultRequestOptions()
+	WithRetry(100, 100*time.Millisecond).apply(opts)
+	if d := opts.retryDelay(3); d != 400*time.Millisecond {
+		t.Fatalf("第 3 次失败后期望等待 400ms, 得到 %s", d)
+	}
+	// 重试次数很多时不溢出, 等待时间封顶
+	for _, attempt := range []int{20, 64, 100} {
+		if d := opts.retryDelay(attempt); d != maxRetryDelay {
+			t.Fatalf("第 %d 次失败后期望等待 %s, 得到 %s", attempt, maxRetryDelay, d)
+		}
+	}
+
+	// WithMaxRetryDelay 替换默认的上限, baseDelay 本身超过上限时也按上限等待
+	capped := defaultRequestOptions()
+	WithRetry(100, 100*time.Millisecond).apply(capped)
+	WithMaxRetryDelay(250 * time.Millisecond).apply(capped)
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 250 * time.Millisecond, 100: 250 * time.Millisecond} {
+		if d := capped.retryDelay(attempt); d != want {
+			t.Fatalf("设置上限后第 %d 次失败期望等待 %s, 得到 %s", attempt, want, d)
+		}
+	}
+	WithRetry(3, time.Second).apply(capped)
+	if d := capped.retryDelay(1); d != 250*time.Millisecond {
+		t.Fatalf("baseDelay 超过上限时期望等待 250ms, 得到 %s", d)
+	}
+	if err := WithMaxRetryDelay(0).apply(capped); err == nil {
+		t.Fatal("上限不是正数时期望返回错误")
+	}
+
+	WithRetryJitter().apply(opts)
+	for i := 0; i < 1000; i++ {
+		if d := opts.retryDelay(1); d < 50*time.Millisecond || d >= 100*time.Millisecond {
+			t.Fatalf("抖动后的等待时间应在 [50ms, 100ms) 之间, 得到 %s", d)
+		}
+	}
+}
+
+// TestRetryWithBodyObserver 测试读取响应体中途断开时, 被观察的响应体不重试, 避免重复回调
+func TestRetryWithBodyObserver(t *testing.T) {
+	ResetDefaultClient()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			// 声明的长度比实际写出的多, 客户端读到 unexpected EOF
+			w.Header().Set("Content-Length", "10")
+			w.Write([]byte("01234"))
+			return
+		}
+		w.Write([]byte("0123456789"))
+	}))
+	defer server.Close()
+
+	var observed int
+	_, _, err := Get(context.Background(), server.URL, WithRetry(3, time.Millisecond), WithBodyObserver(func(chunk []byte) { observed += len(chunk) }))
+	if !errors.Is(err, io.ErrUnexpectedEOF) || requests.Load() != 1 || observed != 5 {
+		t.Fatalf("期望 unexpected EOF 且不重试, 得到 %v, 请求 %d 次, 观察到 %d 字节", err, requests.Load(), observed)
+	}
+}
+
+// TestRetryHandledStatusStream 测试 WithStatusHandler 处理的状态码已经把响应体交给流式处理后, 不再重试或尝试备用地址
+func TestRetryHandledStatusStream(t *testing.T) {
+	ResetDefaultClient()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"n":1}` + "\n"))
+			return
+		}
+		w.Write([]byte(`{"n":2}` + "\n"))
+	}))
+	defer server.Close()
+
+	errUnavailable := errors.New("unavailable")
+	handler := WithStatusHandler(http.StatusServiceUnavailable, func(respBody []byte, header http.Header) error {
+		return errUnavailable
+	})
+	for name, option := range map[string]Option{
+		"重试":   WithRetry(3, time.Millisecond),
+		"备用地址": WithFallbackURLs(server.URL),
+	} {
+		requests.Store(0)
+		records, errc := StreamNDJSON[struct{ N int }](context.Background(), server.URL, handler, option)
+		var got []int
+		for record := range records {
+			got = append(got, record.N)
+		}
+		if err := <-errc; !errors.Is(err, errUnavailable) || len(got) != 1 || got[0] != 1 || requests.Load() != 1 {
+			t.Fatalf("%s: 期望只交出第一次响应的记录并返回处理函数的错误, 得到 %v %v, 请求 %d 次", name, got, err, requests.Load())
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package httptool
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+)
+
+var connReuse = &connReuseMonitor{hosts: map[string]*connReuseCounter{}}
+
+// connReuseMonitor 按 host 统计拿到的连接中新建连接的比例
+type connReuseMonitor struct {
+	mu       sync.Mutex
+	window   int     // 每个 host 每完成多少个请求统计一次, 0 表示关闭监控
+	maxRatio float64 // 新建连接占比超过该值时告警
+	hosts    map[string]*connReuseCounter
+}
+
+type connReuseCounter struct {
+	requests int
+	newConns int
+}
+
+// SetConnReuseWarning 开启连接复用率监控: 每个 host 每拿到 window 次连接统计一次, 新建连接的占比超过 maxNewConnRatio 时输出一条 Warn 日志
+// 复用率低通常说明配置有问题, 如响应体没有读完就关闭、服务端返回 Connection: close、请求头里设置了 Connection: close、MaxIdleConnsPerHost 太小
+// window <= 0 时关闭监控; 日志通过触发统计的那个请求的 logger 输出
+func SetConnReuseWarning(window int, maxNewConnRatio float64) {
+	connReuse.mu.Lock()
+	defer connReuse.mu.Unlock()
+	if window < 0 {
+		window = 0
+	}
+	connReuse.window, connReuse.maxRatio = window, maxNewConnRatio
+	connReuse.hosts = map[string]*connReuseCounter{}
+}
+
+// record 记录一次拿到连接, 一个统计窗口结束且新建连接占比过高时返回 warn
+func (m *connReuseMonitor) record(host string, reused bool) (warn bool, requests int, newConns int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.window == 0 {
+		return
+	}
+	c, ok := m.hosts[host]
+	if !ok {
+		c = &connReuseCounter{}
+		m.hosts[host] = c
+	}
+	c.requests++
+	if !reused {
+		c.newConns++
+	}
+	if c.requests < m.window {
+		return
+	}
+	requests, newConns = c.requests, c.newConns
+	*c = connReuseCounter{} // 每个窗口单独统计, 持续异常时周期性告警
+	return float64(newConns)/float64(requests) > m.maxRatio, requests, newConns
+}
+
+// traceConnReuse 开启了连接复用率监控时给请求挂载统计钩子
+func (opts *requestOption) traceConnReuse(req *http.Request) *http.Request {
+	connReuse.mu.Lock()
+	enabled := connReuse.window > 0
+	connReuse.mu.Unlock()
+	if !enabled {
+		return req
+	}
+	host := req.URL.Host
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if warn, requests, newConns := connReuse.record(host, info.Reused); warn {
+				opts.logger.Warn(opts.ctx, "HTTP_CONN_REUSE_LOW", opts.withLoggerFields("host", host, "requests", requests, "new_conns", newConns)...)
+			}
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
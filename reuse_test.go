@@ -0,0 +1,48 @@
+package httptool
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestSetConnReuseWarning 测试连接复用率过低时告警
+func TestSetConnReuseWarning(t *testing.T) {
+	ResetDefaultClient()
+	SetConnReuseWarning(4, 0.5)
+	defer SetConnReuseWarning(0, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("close") != "" {
+			w.Header().Set("Connection", "close") // 每次都断开连接, 无法复用
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	t.Run("连接正常复用", func(t *testing.T) {
+		logger := &MockLogger{}
+		for i := 0; i < 4; i++ {
+			if _, _, err := Get(ctx, server.URL, WithLogger(logger)); err != nil {
+				t.Fatalf("请求失败: %v", err)
+			}
+		}
+		if logger.warnCalled {
+			t.Fatal("连接复用正常时不应告警")
+		}
+	})
+
+	t.Run("服务端关闭连接", func(t *testing.T) {
+		logger := &MockLogger{}
+		for i := 0; i < 4; i++ {
+			if _, _, err := Get(ctx, server.URL+"?close=1", WithLogger(logger)); err != nil {
+				t.Fatalf("请求失败: %v", err)
+			}
+		}
+		if !logger.warnCalled { // 告警之后请求本身还会输出 debug 日志, 这里只检查是否告警
+			t.Fatal("期望输出连接复用率告警")
+		}
+	})
+}
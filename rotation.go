@@ -0,0 +1,162 @@
+package httptool
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithConnectionRotation 限制一条连接最多发送 maxRequests 个请求, 之后关闭它并新建连接
+// 用于 L4 负载均衡把连接固定到某个后端的场景, 定期换连接让请求更均匀地分布到各个后端
+// 第 maxRequests 个请求会带上 Connection: close, 服务端响应后连接即被关闭
+// HTTP/2 的连接不能这样关闭, 所以派生的 Transport 只使用 HTTP/1.1
+func WithConnectionRotation(maxRequests int) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		if maxRequests <= 0 {
+			return fmt.Errorf("max requests per connection must be positive, got %d", maxRequests)
+		}
+		opts.transport.maxConnRequests = maxRequests
+		return
+	})
+}
+
+// WithMaxConnAge 连接建立超过 d 之后不再复用, 不论期间是否空闲: 空闲的连接到时立即关闭, 正在处理请求的连接在这个请求结束后关闭,
+// 到时前刚好拿到连接的请求会带上 Connection: close; 之后的请求使用新建的连接
+// 用于某些负载均衡会让长期存在的连接失效的场景, 同时让连接定期重新解析 DNS 以感知后端地址的变化
+// 与 WithConnectionRotation 一样, 派生的 Transport 只使用 HTTP/1.1
+func WithMaxConnAge(d time.Duration) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		if d <= 0 {
+			return fmt.Errorf("max connection age must be positive, got %s", d)
+		}
+		opts.transport.maxConnAge = d
+		return
+	})
+}
+
+// disableHTTP2 派生的 Transport 只使用 HTTP/1.1, 不与服务端协商 h2
+// HTTP/2 的请求都复用同一条连接, Connection: close 对它不起作用
+func disableHTTP2(tr *http.Transport) {
+	tr.ForceAttemptHTTP2 = false
+	tr.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{} // 非 nil 的空 map 关闭自动启用的 HTTP/2
+	if tr.TLSClientConfig != nil {
+		// 原 Transport 用过 HTTP/2 后 NextProtos 中会有 h2, 协商出 h2 时派生 Transport 无法处理
+		tr.TLSClientConfig.NextProtos = slices.DeleteFunc(slices.Clone(tr.TLSClientConfig.NextProtos), func(proto string) bool {
+			return proto == "h2"
+		})
+	}
+}
+
+// countedConn 记录连接的建立时间和连接上发送过的请求数, 设置了连接时长上限时到时关闭连接
+type countedConn struct {
+	net.Conn
+	created  time.Time
+	requests atomic.Int64
+
+	mu      sync.Mutex
+	timer   *time.Timer // 到达连接时长上限时调用 expire
+	busy    bool        // 正在处理请求, 从拿到连接到归还连接池
+	expired bool        // 已经超过连接时长上限
+}
+
+// countRequestsDial 包装 DialContext, 建立的连接可以统计请求数和连接时长, maxAge 大于 0 时连接到时关闭
+func countRequestsDial(dial func(ctx context.Context, network, addr string) (net.Conn, error), maxAge time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		c := &countedConn{Conn: conn, created: time.Now()}
+		if maxAge > 0 {
+			c.timer = time.AfterFunc(maxAge, c.expire)
+		}
+		return c, nil
+	}
+}
+
+// expire 连接到达时长上限: 空闲时立即关闭, Transport 发现连接断开后把它移出连接池; 正在处理请求时等请求结束再关闭
+func (c *countedConn) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expired = true
+	if !c.busy {
+		c.Conn.Close()
+	}
+}
+
+// acquire 开始在连接上处理请求, 返回连接是否已经超过时长上限
+func (c *countedConn) acquire() (expired bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.busy = true
+	return c.expired
+}
+
+// release 请求结束后连接归还连接池, 期间超过时长上限的连接直接关闭
+func (c *countedConn) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.busy = false
+	if c.expired {
+		c.Conn.Close()
+	}
+}
+
+func (c *countedConn) Close() error {
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	return c.Conn.Close()
+}
+
+// unwrapCountedConn 取出连接底层的 countedConn, HTTP/2 连接不能通过 Connection: close 关闭, 返回 false
+func unwrapCountedConn(conn net.Conn) (*countedConn, bool) {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if tlsConn.ConnectionState().NegotiatedProtocol == "h2" {
+			return nil, false
+		}
+		conn = tlsConn.NetConn()
+	}
+	c, ok := conn.(*countedConn)
+	return c, ok
+}
+
+// traceRotation 拿到连接时累计请求数, 达到请求数上限或连接时长上限的请求带上 Connection: close
+func (opts *requestOption) traceRotation(req *http.Request) *http.Request {
+	maxRequests := int64(opts.transport.maxConnRequests)
+	maxAge := opts.transport.maxConnAge
+	if maxRequests == 0 && maxAge == 0 {
+		return req
+	}
+	// 请求头 map 在请求的各个副本间共享, GotConn 在写出请求之前调用, 这里设置的请求头会随请求发出
+	header := req.Header
+	var conn *countedConn
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			c, ok := unwrapCountedConn(info.Conn)
+			if !ok {
+				return
+			}
+			conn = c
+			expired := c.acquire()
+			requests := c.requests.Add(1)
+			if (maxRequests > 0 && requests >= maxRequests) || (maxAge > 0 && (expired || time.Since(c.created) >= maxAge)) {
+				header.Set("Connection", "close")
+			}
+		},
+		// 读完响应体后连接归还连接池时调用
+		PutIdleConn: func(err error) {
+			if conn != nil {
+				conn.release()
+			}
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
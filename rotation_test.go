@@ -0,0 +1,153 @@
+package httptool
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestWithConnectionRotation 测试连接发送一定数量的请求后换新连接
+func TestWithConnectionRotation(t *testing.T) {
+	ResetDefaultClient()
+
+	remoteAddrs := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteAddrs[r.RemoteAddr]++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	for i := 0; i < 6; i++ {
+		if _, _, err := Get(context.Background(), server.URL, WithConnectionRotation(2)); err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+	}
+	if len(remoteAddrs) != 3 {
+		t.Fatalf("期望使用 3 条连接, 得到 %v", remoteAddrs)
+	}
+	for addr, n := range remoteAddrs {
+		if n != 2 {
+			t.Fatalf("每条连接应发送 2 个请求, %s 发送了 %d 个", addr, n)
+		}
+	}
+
+	if _, _, err := Get(context.Background(), server.URL, WithConnectionRotation(0)); err == nil {
+		t.Fatal("maxRequests 为 0 时期望返回错误")
+	}
+}
+
+// TestConnectionRotationHTTP2 测试服务端支持 HTTP/2 时, 连接轮换改用 HTTP/1.1 并且仍然生效
+func TestConnectionRotationHTTP2(t *testing.T) {
+	ResetDefaultClient()
+	defer ResetDefaultClient()
+
+	var protos []int
+	remoteAddrs := map[string]int{}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		protos = append(protos, r.ProtoMajor)
+		remoteAddrs[r.RemoteAddr]++
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	SetHttpClient(server.Client())
+
+	ctx := context.Background()
+	if _, _, err := Get(ctx, server.URL); err != nil || protos[0] != 2 {
+		t.Fatalf("不设置轮换时期望使用 HTTP/2, 得到 HTTP/%d %v", protos[0], err)
+	}
+
+	protos, remoteAddrs = nil, map[string]int{}
+	for i := 0; i < 4; i++ {
+		if _, _, err := Get(ctx, server.URL, WithConnectionRotation(2)); err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+	}
+	for _, proto := range protos {
+		if proto != 1 {
+			t.Fatalf("设置轮换时期望使用 HTTP/1.1, 得到 %v", protos)
+		}
+	}
+	if len(remoteAddrs) != 2 {
+		t.Fatalf("期望使用 2 条连接, 得到 %v", remoteAddrs)
+	}
+}
+
+// TestWithMaxConnAge 测试连接超过使用时长后关闭, 不论是否空闲
+func TestWithMaxConnAge(t *testing.T) {
+	ResetDefaultClient()
+
+	var mu sync.Mutex
+	var remoteAddrs []string
+	closedConns := make(chan string, 100)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		remoteAddrs = append(remoteAddrs, r.RemoteAddr)
+		mu.Unlock()
+		if r.URL.Path == "/slow" {
+			time.Sleep(150 * time.Millisecond) // 处理请求期间连接到达时长上限
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closedConns <- conn.RemoteAddr().String()
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	get := func(path string) string {
+		t.Helper()
+		if _, _, err := Get(context.Background(), server.URL+path, WithMaxConnAge(100*time.Millisecond)); err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return remoteAddrs[len(remoteAddrs)-1]
+	}
+	waitClosed := func(addr string) {
+		t.Helper()
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case closed := <-closedConns:
+				if closed == addr {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("连接 %s 超过使用时长后应被关闭", addr)
+			}
+		}
+	}
+
+	t.Run("空闲的连接到时关闭", func(t *testing.T) {
+		first := get("/")
+		if second := get("/"); second != first {
+			t.Fatalf("使用时长内应复用连接, 得到 %s 和 %s", first, second)
+		}
+		// 连接一直空闲, 没有再发出请求也会被关闭
+		waitClosed(first)
+		if third := get("/"); third == first {
+			t.Fatal("到时后的请求应使用新连接")
+		}
+	})
+
+	t.Run("处理请求的连接在请求结束后关闭", func(t *testing.T) {
+		ResetDefaultClient()
+		addr := get("/slow")
+		waitClosed(addr)
+		if next := get("/"); next == addr {
+			t.Fatal("到时后的请求应使用新连接")
+		}
+	})
+
+	if _, _, err := Get(context.Background(), server.URL, WithMaxConnAge(0)); err == nil {
+		t.Fatal("d 为 0 时期望返回错误")
+	}
+}
@@ -0,0 +1,67 @@
+package httptool
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// Session 会话, 会话内对同一个 host 的请求都通过同一条专用连接发送
+// 用于依赖连接级状态的后端协议, 并发的会话请求会排队等待这条连接
+type Session struct {
+	client *http.Client
+}
+
+// NewSession 基于全局客户端创建会话, 会话独占一个每个 host 只有一条连接的连接池
+// 用完后调用 Close 关闭连接
+func NewSession() (*Session, error) {
+	c := GetHttpClient()
+	var base *http.Transport
+	switch tr := c.Transport.(type) {
+	case nil:
+		base = http.DefaultTransport.(*http.Transport)
+	case *http.Transport:
+		base = tr
+	default:
+		return nil, fmt.Errorf("session requires an *http.Transport, got %T", c.Transport)
+	}
+
+	tr := cloneTransport(base)
+	enableWarmDial(tr)
+	tr.DisableKeepAlives = false
+	tr.MaxConnsPerHost = 1
+	tr.MaxIdleConnsPerHost = 1
+	sessionClient := *c
+	sessionClient.Transport = tr
+	return &Session{client: &sessionClient}, nil
+}
+
+// Request 在会话的连接上发起请求
+func (s *Session) Request(method string, url string, options ...Option) (httpStatusCode int, respBody []byte, err error) {
+	return Request(method, url, append(slices.Clip(options), s.option())...)
+}
+
+// Get 在会话的连接上发起GET请求
+func (s *Session) Get(ctx context.Context, url string, options ...Option) (httpStatusCode int, respBody []byte, err error) {
+	return Get(ctx, url, append(slices.Clip(options), s.option())...)
+}
+
+// Post 在会话的连接上发起POST请求
+func (s *Session) Post(ctx context.Context, url string, data []byte, options ...Option) (httpStatusCode int, respBody []byte, err error) {
+	return Post(ctx, url, data, append(slices.Clip(options), s.option())...)
+}
+
+// Close 关闭会话的连接
+func (s *Session) Close() {
+	s.client.CloseIdleConnections()
+	forgetTransport(s.client.Transport.(*http.Transport))
+}
+
+// option 让请求使用会话的客户端
+func (s *Session) option() Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.client, err = s.client, nil
+		return
+	})
+}
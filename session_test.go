@@ -0,0 +1,46 @@
+package httptool
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// TestSession 测试会话内的请求使用同一条连接
+func TestSession(t *testing.T) {
+	ResetDefaultClient()
+
+	var mu sync.Mutex
+	remoteAddrs := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		remoteAddrs[r.RemoteAddr]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	session, err := NewSession()
+	if err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+	defer session.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := session.Get(context.Background(), server.URL); err != nil {
+				t.Errorf("请求失败: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(remoteAddrs) != 1 {
+		t.Fatalf("会话内的请求应使用同一条连接, 得到 %v", remoteAddrs)
+	}
+}
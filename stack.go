@@ -0,0 +1,57 @@
+package httptool
+
+import (
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// maxStackDepth WithStackOnError 最多记录的调用栈层数
+const maxStackDepth = 32
+
+// packagePrefix 本包函数名的前缀, 记录调用栈时跳过本包内部的调用
+var packagePrefix = reflect.TypeOf(requestOption{}).PkgPath() + "."
+
+// WithStackOnError 请求返回错误时用 Error 级别记录发起请求的调用栈(最多 32 层), 用于在调用点很多的代码中定位是哪里发起的失败请求
+// 调用栈只在出错时获取, 成功的请求没有额外开销
+func WithStackOnError() Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.stackOnError = true
+		return
+	})
+}
+
+// logErrorStack 记录请求出错时的调用栈
+func (opts *requestOption) logErrorStack(method string, url string, err error) {
+	opts.logger.Error(opts.ctx, "HTTP_REQUEST_ERROR_STACK", opts.withLoggerFields("method", method, "url", url, "err", err, "stack", callerStack())...)
+}
+
+// isPackageFrame 函数是否属于本包, 按函数名中的包路径判断; 子包(如 httptooltest)和外部测试包 httptool_test 不属于本包
+func isPackageFrame(function string) bool {
+	return strings.HasPrefix(function, packagePrefix)
+}
+
+// callerStack 返回调用方的调用栈, 跳过最内层的本包函数
+func callerStack() string {
+	pcs := make([]uintptr, maxStackDepth+16) // 多取一些, 留给跳过的本包函数
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	var b strings.Builder
+	depth, inPackage := 0, true
+	for depth < maxStackDepth {
+		frame, more := frames.Next()
+		if inPackage && isPackageFrame(frame.Function) {
+			if !more {
+				break
+			}
+			continue
+		}
+		inPackage = false
+		b.WriteString(frame.Function + "\n\t" + frame.File + ":" + strconv.Itoa(frame.Line) + "\n")
+		depth++
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
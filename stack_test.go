@@ -0,0 +1,64 @@
+package httptool
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestWithStackOnError 测试请求出错时记录调用栈
+func TestWithStackOnError(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	mockLogger := &MockLogger{}
+	if _, _, err := Get(ctx, server.URL, WithLogger(mockLogger), WithStackOnError()); err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if mockLogger.errorCalled {
+		t.Fatal("成功的请求不应记录调用栈")
+	}
+
+	if _, _, err := Get(ctx, server.URL+"/error", WithLogger(mockLogger), WithStackOnError()); err == nil {
+		t.Fatal("期望返回错误")
+	}
+	if !mockLogger.errorCalled || mockLogger.lastMsg != "HTTP_REQUEST_ERROR_STACK" {
+		t.Fatalf("期望记录调用栈, 得到 %q", mockLogger.lastMsg)
+	}
+	var stack string
+	for i := 0; i+1 < len(mockLogger.lastData); i += 2 {
+		if mockLogger.lastData[i] == "stack" {
+			stack = mockLogger.lastData[i+1].(string)
+		}
+	}
+	// 测试函数同样属于本包, 调用栈从调用它的 testing 包开始, 不包含本包内部的函数
+	if !strings.HasPrefix(stack, "testing.tRunner") || strings.Contains(stack, "RequestWithResponse") {
+		t.Fatalf("调用栈不符合预期:\n%s", stack)
+	}
+}
+
+// TestIsPackageFrame 测试按包路径判断调用栈中的函数是否属于本包
+func TestIsPackageFrame(t *testing.T) {
+	for function, want := range map[string]bool{
+		packagePrefix + "Get":                                   true,
+		packagePrefix + "(*requestOption).send":                 true,
+		packagePrefix + "RequestWithResponse.func1":             true,
+		packagePrefix + "streamRecords[...].func2":              true,
+		"github.com/jayzyc/httptool_test.TestGet":               false,
+		"github.com/jayzyc/httptool/httptooltest.Install.func1": false,
+		"main.main": false,
+	} {
+		if got := isPackageFrame(function); got != want {
+			t.Fatalf("%s 期望 %v, 得到 %v", function, want, got)
+		}
+	}
+}
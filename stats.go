@@ -0,0 +1,60 @@
+package httptool
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+// Stats 请求的统计信息, 通过 WithStats 传入, 请求结束后填充
+// 设置了备用地址时记录的是最后一次尝试的信息
+type Stats struct {
+	RemoteAddr string // 实际连接的服务端地址, 用于定位 VIP 后面具体是哪个实例处理了请求
+
+	// 连接池的使用情况, 用于判断延迟来自连接池排队还是服务端
+	ConnReused   bool          // 连接是否复用自连接池, false 表示新建的连接
+	ConnIdleTime time.Duration // 复用的连接在连接池中空闲的时间
+	// 从向连接池要连接到拿到连接的耗时; 复用连接时是排队等待的时间(如受 MaxConnsPerHost 限制),
+	// 新建连接时还包括 DNS 解析、拨号和 TLS 握手
+	PoolWait time.Duration
+
+	// WithCompressToFile 写入文件的响应体压缩前后的字节数
+	UncompressedBytes int64
+	CompressedBytes   int64
+
+	IdempotencyKey string // WithIdempotencyKey 生成的幂等键
+	Truncated      bool   // 响应体是否被 WithBodyReadLimit 截断
+	HedgeAttempt   int    // WithHedging 时胜出的请求, 0 为最初的请求, 1 起为对冲请求
+	Attempts       int    // 发送的次数, 设置了 WithRetry 时包括重试
+}
+
+// WithStats 收集请求的统计信息写入 stats
+// 统计依赖在请求上挂载 httptrace 钩子, 不需要时不设置就没有额外开销
+func WithStats(stats *Stats) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.stats, err = stats, nil
+		return
+	})
+}
+
+// traceRequest 给请求挂载收集统计信息的 httptrace 钩子
+func (opts *requestOption) traceRequest(req *http.Request) *http.Request {
+	if opts.stats == nil {
+		return req
+	}
+	stats := opts.stats
+	var getConn time.Time
+	trace := &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			getConn = time.Now()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			stats.RemoteAddr = info.Conn.RemoteAddr().String()
+			stats.ConnReused, stats.ConnIdleTime = info.Reused, info.IdleTime
+			if !getConn.IsZero() {
+				stats.PoolWait = time.Since(getConn)
+			}
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
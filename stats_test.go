@@ -0,0 +1,55 @@
+package httptool
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWithStatsRemoteAddr 测试记录实际连接的服务端地址
+func TestWithStatsRemoteAddr(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var stats Stats
+	_, _, err := Get(context.Background(), server.URL, WithStats(&stats))
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if want := strings.TrimPrefix(server.URL, "http://"); stats.RemoteAddr != want {
+		t.Fatalf("期望服务端地址 %s, 得到 %s", want, stats.RemoteAddr)
+	}
+}
+
+// TestWithStatsConnPool 测试记录连接池的使用情况
+func TestWithStatsConnPool(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	var first, second Stats
+	if _, _, err := Get(ctx, server.URL, WithStats(&first)); err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if _, _, err := Get(ctx, server.URL, WithStats(&second)); err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if first.ConnReused || first.PoolWait <= 0 {
+		t.Fatalf("第一个请求应新建连接, 得到 %+v", first)
+	}
+	if !second.ConnReused || second.ConnIdleTime < 10*time.Millisecond {
+		t.Fatalf("第二个请求应复用空闲的连接, 得到 %+v", second)
+	}
+}
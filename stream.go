@@ -0,0 +1,196 @@
+package httptool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// ErrStreamUnsupported 流式处理响应体(StreamNDJSON、StreamJSONArray、RequestStream)与并行发出多个请求或共享响应体的选项
+// (WithHedging、WithDedupeWindow)一起使用时返回, 这些选项会让同一份响应体被交出多次
+var ErrStreamUnsupported = errors.New("option is not supported by streaming requests")
+
+// withBodyConsumer 响应体交给 consume 流式处理, 不再读入内存, 请求返回的响应体为nil
+// 需要放在其他选项之后, 才能检查出不支持的选项
+func withBodyConsumer(consume func(ctx context.Context, r io.Reader) error) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		if opts.hedgeAfter > 0 || opts.dedupeKey != "" {
+			return ErrStreamUnsupported
+		}
+		opts.bodyConsumer = consume
+		return
+	})
+}
+
+// StreamNDJSON 发起GET请求, 把 NDJSON(每行一个JSON)响应逐行解析为 T 发送到返回的 channel, 适合日志流、批量导出这类接口
+// 记录 channel 在响应读完或出错后关闭, 之后错误 channel 最多返回一个错误再关闭; 空行会被跳过
+// 上下文取消时停止读取; WithTimeout 同样限制整个流的读取时间, 长时间的流请相应地调大
+// 不支持 WithHedging 和 WithDedupeWindow, 一起使用时错误 channel 返回 ErrStreamUnsupported
+func StreamNDJSON[T any](ctx context.Context, url string, options ...Option) (<-chan T, <-chan error) {
+	return streamRecords(ctx, url, options, func(r io.Reader, emit func(T) error) error {
+		br := bufio.NewReader(r)
+		for line := 1; ; line++ {
+			// ReadBytes 会跨越缓冲区边界读完整行, 最后一行可以没有换行符
+			data, err := br.ReadBytes('\n')
+			if data = bytes.TrimSpace(data); len(data) > 0 {
+				var record T
+				if err := json.Unmarshal(data, &record); err != nil {
+					return fmt.Errorf("decode ndjson line %d: %w", line, err)
+				}
+				if err := emit(record); err != nil {
+					return err
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// StreamJSONArray 发起GET请求, 用 json.Decoder 逐个解析JSON数组响应中的元素发送到返回的 channel
+// 不需要把整个数组读入内存, 适合返回超大结果集的接口; channel 和错误的约定与 StreamNDJSON 相同
+func StreamJSONArray[T any](ctx context.Context, url string, options ...Option) (<-chan T, <-chan error) {
+	return streamRecords(ctx, url, options, func(r io.Reader, emit func(T) error) error {
+		dec := json.NewDecoder(r)
+		if err := expectDelim(dec, '['); err != nil {
+			return err
+		}
+		for i := 0; dec.More(); i++ {
+			var record T
+			if err := dec.Decode(&record); err != nil {
+				return fmt.Errorf("decode json array element %d: %w", i, err)
+			}
+			if err := emit(record); err != nil {
+				return err
+			}
+		}
+		return expectDelim(dec, ']')
+	})
+}
+
+// expectDelim 读取下一个token并检查是否为指定的分隔符
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("read json array: %w", err)
+	}
+	if token != delim {
+		return fmt.Errorf("read json array: expected %q, got %v", delim, token)
+	}
+	return nil
+}
+
+// streamRecords 在后台发起请求, 用 decode 流式解析响应体, 解析出的记录通过 emit 发送到返回的 channel
+func streamRecords[T any](ctx context.Context, url string, options []Option, decode func(r io.Reader, emit func(T) error) error) (<-chan T, <-chan error) {
+	records := make(chan T)
+	errc := make(chan error, 1)
+	consume := func(ctx context.Context, r io.Reader) error {
+		return decode(r, func(record T) error {
+			// 接收方不再读取且上下文结束时返回上下文的错误
+			select {
+			case records <- record:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}
+
+	go func() {
+		defer close(errc)
+		options = append(slices.Clip(options), withBodyConsumer(consume), WithContext(ctx))
+		_, _, err := Request("GET", url, options...)
+		close(records)
+		if err != nil {
+			errc <- err
+		}
+	}()
+	return records, errc
+}
+
+// RequestStream 发起请求, 拿到成功的响应后直接返回响应体, 不读入内存, 适合下载大文件这类响应体很大的接口
+// 调用方负责 Close 返回的 body, 没有读完也必须 Close; 非成功的响应与 RequestWithResponse 一样返回 *HTTPStatusError
+// 请求的超时(包括默认超时)限制到 Close 为止的整个读取过程, 读取很慢的大响应体请相应地调大 WithTimeout
+// WithMaxResponseBytes 同样生效, 读到超过上限时 Read 返回 ErrBodyTooLarge; 重试只发生在开始返回响应体之前
+func RequestStream(method string, url string, options ...Option) (httpStatusCode int, header http.Header, body io.ReadCloser, err error) {
+	pr, pw := io.Pipe()
+	ready := make(chan struct{})
+	var readyOnce sync.Once
+	finished := make(chan struct{})
+	var cancel context.CancelFunc = func() {}
+	var gotStatus int
+	var gotHeader http.Header
+	consume := func(ctx context.Context, r io.Reader) error {
+		// 响应体交出去之后不会再重试, consume 只调用一次; 仍然防止重复 close
+		readyOnce.Do(func() { close(ready) })
+		// 调用方读多少这里写多少, Close 之后写入失败, 请求随之结束
+		_, err := io.Copy(pw, r)
+		return err
+	}
+	stream := optionFunc(func(opts *requestOption) (err error) {
+		if err = withBodyConsumer(consume).apply(opts); err != nil {
+			return
+		}
+		// 在调用方已设置的回调之前记录状态码和响应头
+		next := opts.onResponseHeaders
+		opts.onResponseHeaders = func(httpStatusCode int, header http.Header) (bool, error) {
+			gotStatus, gotHeader = httpStatusCode, header
+			if next != nil {
+				return next(httpStatusCode, header)
+			}
+			return true, nil
+		}
+		// Close 时取消请求, 正在阻塞读取响应体的连接也会立即结束
+		opts.ctx, cancel = context.WithCancel(opts.ctx)
+		return
+	})
+
+	var result struct {
+		httpStatusCode int
+		header         http.Header
+		err            error
+	}
+	go func() {
+		defer close(finished)
+		result.httpStatusCode, result.header, _, result.err = RequestWithResponse(method, url, append(slices.Clip(options), stream)...)
+		// 请求的最终结果交给调用方的 Read: 成功时读到 EOF, 失败时读到请求的错误
+		pw.CloseWithError(result.err)
+	}()
+
+	select {
+	case <-ready:
+	case <-finished:
+		select {
+		case <-ready: // 响应体为空时, 请求可能在这里之前就已经完成
+		default:
+			cancel()
+			return result.httpStatusCode, result.header, nil, result.err
+		}
+	}
+	return gotStatus, gotHeader, &streamBody{PipeReader: pr, cancel: cancel, finished: finished}, nil
+}
+
+// streamBody RequestStream 返回的响应体, Close 时结束请求并等待请求返回
+type streamBody struct {
+	*io.PipeReader
+	cancel   context.CancelFunc
+	finished <-chan struct{}
+}
+
+func (s *streamBody) Close() error {
+	s.PipeReader.Close()
+	s.cancel()
+	<-s.finished
+	return nil
+}
@@ -0,0 +1,293 @@
+package httptool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestStreamNDJSON 测试逐行解析 NDJSON 响应
+func TestStreamNDJSON(t *testing.T) {
+	ResetDefaultClient()
+
+	type event struct {
+		ID int `json:"id"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		if r.URL.Query().Get("bad") != "" {
+			w.Write([]byte("{\"id\":1}\nnot json\n"))
+			return
+		}
+		// 一行拆成多次写出, 模拟记录跨越缓冲区边界; 最后一行没有换行符
+		w.Write([]byte("{\"id\":1}\n\n{\"i"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("d\":2}\n{\"id\":3}"))
+	}))
+	defer server.Close()
+
+	t.Run("逐条返回", func(t *testing.T) {
+		records, errc := StreamNDJSON[event](context.Background(), server.URL)
+		var ids []int
+		for e := range records {
+			ids = append(ids, e.ID)
+		}
+		if err := <-errc; err != nil {
+			t.Fatalf("读取失败: %v", err)
+		}
+		if len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 {
+			t.Fatalf("期望 [1 2 3], 得到 %v", ids)
+		}
+	})
+
+	t.Run("解析失败", func(t *testing.T) {
+		records, errc := StreamNDJSON[event](context.Background(), server.URL+"?bad=1")
+		count := 0
+		for range records {
+			count++
+		}
+		if err := <-errc; err == nil || count != 1 {
+			t.Fatalf("期望读到 1 条后返回解析错误, 得到 %d 条 %v", count, err)
+		}
+	})
+
+	t.Run("不支持对冲和去重", func(t *testing.T) {
+		for _, opt := range []Option{WithHedging(time.Millisecond, 1), WithDedupeWindow("ndjson", time.Second)} {
+			records, errc := StreamNDJSON[event](context.Background(), server.URL, opt)
+			n := 0
+			for range records {
+				n++
+			}
+			if err := <-errc; !errors.Is(err, ErrStreamUnsupported) || n != 0 {
+				t.Fatalf("期望 ErrStreamUnsupported 且没有记录, 得到 %d 条 %v", n, err)
+			}
+		}
+	})
+
+	t.Run("取消后停止", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		records, errc := StreamNDJSON[event](ctx, server.URL)
+		<-records
+		cancel() // 不再读取剩余的记录
+		for range records {
+		}
+		if err := <-errc; err == nil {
+			t.Fatal("取消后期望返回错误")
+		}
+	})
+}
+
+// TestStreamJSONArray 测试逐个解析JSON数组元素
+func TestStreamJSONArray(t *testing.T) {
+	ResetDefaultClient()
+
+	type item struct {
+		ID int `json:"id"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		if r.URL.Query().Get("object") != "" {
+			w.Write([]byte(`{"id":1}`))
+			return
+		}
+		w.Write([]byte(`[{"id":1},`))
+		w.(http.Flusher).Flush()
+		w.Write([]byte(` {"id":2}, {"id":3}]`))
+	}))
+	defer server.Close()
+
+	t.Run("逐个返回", func(t *testing.T) {
+		records, errc := StreamJSONArray[item](context.Background(), server.URL)
+		var ids []int
+		for e := range records {
+			ids = append(ids, e.ID)
+		}
+		if err := <-errc; err != nil {
+			t.Fatalf("读取失败: %v", err)
+		}
+		if len(ids) != 3 || ids[0] != 1 || ids[2] != 3 {
+			t.Fatalf("期望 [1 2 3], 得到 %v", ids)
+		}
+	})
+
+	t.Run("响应不是数组", func(t *testing.T) {
+		records, errc := StreamJSONArray[item](context.Background(), server.URL+"?object=1")
+		for range records {
+		}
+		if err := <-errc; err == nil {
+			t.Fatal("响应不是数组时期望返回错误")
+		}
+	})
+}
+
+// TestRequestStream 测试直接返回响应体的流式请求
+func TestRequestStream(t *testing.T) {
+	ResetDefaultClient()
+
+	payload := bytes.Repeat([]byte("0123456789"), 100000)
+	release := make(chan struct{})
+	handlerDone := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		case "/error":
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("upstream down"))
+		case "/endless":
+			// 先写出一部分, 之后一直写到客户端断开
+			defer func() { handlerDone <- struct{}{} }()
+			w.Write([]byte("first chunk"))
+			w.(http.Flusher).Flush()
+			<-release
+			for r.Context().Err() == nil {
+				if _, err := w.Write(make([]byte, 32*1024)); err != nil {
+					return
+				}
+			}
+		default:
+			w.Header().Set("X-Total", "1000000")
+			w.Write(payload)
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	t.Run("读取完整响应体", func(t *testing.T) {
+		status, header, body, err := RequestStream("GET", server.URL)
+		if err != nil || status != http.StatusOK || header.Get("X-Total") != "1000000" {
+			t.Fatalf("期望成功返回状态码和响应头, 得到 %d %v %v", status, header, err)
+		}
+		defer body.Close()
+		got, err := io.ReadAll(body)
+		if err != nil || !bytes.Equal(got, payload) {
+			t.Fatalf("响应体不完整: %d 字节 %v", len(got), err)
+		}
+	})
+
+	t.Run("响应体为空", func(t *testing.T) {
+		status, _, body, err := RequestStream("GET", server.URL+"/empty")
+		if err != nil || status != http.StatusNoContent {
+			t.Fatalf("期望 204, 得到 %d %v", status, err)
+		}
+		got, err := io.ReadAll(body)
+		body.Close()
+		if err != nil || len(got) != 0 {
+			t.Fatalf("期望空响应体, 得到 %q %v", string(got), err)
+		}
+	})
+
+	t.Run("非 2xx 不返回响应体", func(t *testing.T) {
+		status, _, body, err := RequestStream("GET", server.URL+"/error")
+		var statusErr *HTTPStatusError
+		if !errors.As(err, &statusErr) || status != http.StatusBadGateway || body != nil || string(statusErr.Body) != "upstream down" {
+			t.Fatalf("期望 *HTTPStatusError 且 body 为 nil, 得到 %d %v %v", status, body, err)
+		}
+	})
+
+	t.Run("没读完时Close结束请求", func(t *testing.T) {
+		_, _, body, err := RequestStream("GET", server.URL+"/endless")
+		if err != nil {
+			t.Fatalf("不应出错: %v", err)
+		}
+		// 服务端还没有写完时就已经拿到响应体
+		buf := make([]byte, len("first chunk"))
+		if _, err = io.ReadFull(body, buf); err != nil || string(buf) != "first chunk" {
+			t.Fatalf("期望读到第一段数据, 得到 %q %v", string(buf), err)
+		}
+		release <- struct{}{}
+		start := time.Now()
+		body.Close()
+		if time.Since(start) > time.Second {
+			t.Fatal("Close 应该立即结束请求")
+		}
+		select {
+		case <-handlerDone:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Close 后服务端应该检测到连接断开")
+		}
+	})
+
+	t.Run("超过响应体上限", func(t *testing.T) {
+		_, _, body, err := RequestStream("GET", server.URL, WithMaxResponseBytes(1000))
+		if err == nil {
+			// 没有在 Content-Length 阶段拦截时读取过程中返回错误
+			_, err = io.ReadAll(body)
+			body.Close()
+		}
+		if !errors.Is(err, ErrBodyTooLarge) {
+			t.Fatalf("期望 ErrBodyTooLarge, 得到 %v", err)
+		}
+	})
+
+	t.Run("不支持对冲请求", func(t *testing.T) {
+		_, _, _, err := RequestStream("GET", server.URL, WithHedging(time.Millisecond, 1))
+		if !errors.Is(err, ErrStreamUnsupported) {
+			t.Fatalf("期望 ErrStreamUnsupported, 得到 %v", err)
+		}
+	})
+}
+
+// TestRequestStreamNoRetryAfterBody 测试开始返回响应体后连接断开时不重试, 避免重复交出数据
+func TestRequestStreamNoRetryAfterBody(t *testing.T) {
+	ResetDefaultClient()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		// 声明的长度比实际写出的多, 客户端读到 unexpected EOF
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte(strings.Repeat("x", 10)))
+	}))
+	defer server.Close()
+
+	_, _, body, err := RequestStream("GET", server.URL, WithRetry(3, time.Millisecond))
+	if err != nil {
+		t.Fatalf("不应出错: %v", err)
+	}
+	_, err = io.ReadAll(body)
+	body.Close()
+	if !errors.Is(err, io.ErrUnexpectedEOF) || calls.Load() != 1 {
+		t.Fatalf("期望 unexpected EOF 且只请求一次, 得到 %v, 请求 %d 次", err, calls.Load())
+	}
+}
+
+// TestRequestStreamHandledStatusRetry 测试 WithStatusHandler 处理的状态码开始返回响应体后, 重试或备用地址都不会再次交出响应体
+func TestRequestStreamHandledStatusRetry(t *testing.T) {
+	ResetDefaultClient()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("busy"))
+	}))
+	defer server.Close()
+
+	errBusy := errors.New("busy")
+	handler := WithStatusHandler(http.StatusServiceUnavailable, func(respBody []byte, header http.Header) error {
+		return errBusy
+	})
+	for name, option := range map[string]Option{
+		"重试":   WithRetry(3, time.Millisecond),
+		"备用地址": WithFallbackURLs(server.URL),
+	} {
+		calls.Store(0)
+		status, _, body, err := RequestStream("GET", server.URL, handler, option)
+		if err != nil || status != http.StatusServiceUnavailable {
+			t.Fatalf("%s: 期望返回 503 的响应体, 得到 %d %v", name, status, err)
+		}
+		data, err := io.ReadAll(body)
+		body.Close()
+		if string(data) != "busy" || !errors.Is(err, errBusy) || calls.Load() != 1 {
+			t.Fatalf("%s: 期望读到响应体后得到处理函数的错误且只请求一次, 得到 %q %v, 请求 %d 次", name, string(data), err, calls.Load())
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package httptool
+
+import (
+	"context"
+	"errors"
+)
+
+type tenantContextKey struct{}
+
+// WithTenant 标记请求所属的租户: 设置 X-Tenant-ID 请求头, 在请求日志中输出 tenant 字段, 并写入请求上下文
+// 自定义的 RoundTripper、日志实现等可以通过 TenantFromContext 取出租户; tenantID 不能为空
+func WithTenant(tenantID string) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		if tenantID == "" {
+			return errors.New("tenant id must not be empty")
+		}
+		opts.tenant = tenantID
+		opts.headers["X-Tenant-ID"] = tenantID
+		if opts.loggerFields == nil {
+			opts.loggerFields = map[string]interface{}{}
+		}
+		opts.loggerFields["tenant"] = tenantID
+		return
+	})
+}
+
+// TenantFromContext 取出 WithTenant 写入上下文的租户
+func TenantFromContext(ctx context.Context) (tenantID string, ok bool) {
+	tenantID, ok = ctx.Value(tenantContextKey{}).(string)
+	return
+}
+
+// contextWithTenant 在全部选项应用完之后把租户写入上下文, 避免被之后的 WithContext 覆盖
+func (opts *requestOption) contextWithTenant() {
+	if opts.tenant != "" {
+		opts.ctx = context.WithValue(opts.ctx, tenantContextKey{}, opts.tenant)
+	}
+}
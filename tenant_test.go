@@ -0,0 +1,35 @@
+package httptool
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+// TestWithTenant 测试租户标记
+func TestWithTenant(t *testing.T) {
+	var gotHeader, gotContext string
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		gotHeader = req.Header.Get("X-Tenant-ID")
+		gotContext, _ = TenantFromContext(req.Context())
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Header: http.Header{}, Request: req}, nil
+	})}
+	SetHttpClient(client)
+	defer ResetDefaultClient()
+
+	logger := &MockLogger{}
+	// WithContext 在 WithTenant 之后设置也不会丢失上下文中的租户
+	if _, _, err := Get(context.Background(), "http://example.com", WithTenant("acme"), WithLogger(logger)); err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if gotHeader != "acme" || gotContext != "acme" {
+		t.Fatalf("期望请求头和上下文中的租户为 acme, 得到 %q %q", gotHeader, gotContext)
+	}
+	if !logKeys(logger.lastData)["tenant"] {
+		t.Fatalf("日志中应包含 tenant 字段, 得到 %v", logger.lastData)
+	}
+
+	if _, _, err := Get(context.Background(), "http://example.com", WithTenant("")); err == nil {
+		t.Fatal("租户为空时期望返回错误")
+	}
+}
@@ -0,0 +1,300 @@
+package httptool
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// transportConfig 需要在派生 Transport 上生效的请求选项
+// 同时作为派生 Transport 缓存key的一部分, 所以只能包含可比较类型的字段
+type transportConfig struct {
+	responseHeaderTimeout time.Duration // 等待响应头的超时时间
+	tlsHandshakeTimeout   time.Duration // TLS握手超时时间
+	dialTimeout           time.Duration // 建立TCP连接的超时时间
+	localAddr             string        // 发起连接使用的本地IP
+	maxConnRequests       int           // 一条连接最多发送的请求数
+	happyEyeballs         bool          // 双栈时并行连接 IPv6 和 IPv4
+	maxConnAge            time.Duration // 连接建立后最多使用多久
+}
+
+// apply 把配置应用到克隆出来的 Transport 上
+func (c transportConfig) apply(tr *http.Transport) {
+	if c.responseHeaderTimeout > 0 {
+		tr.ResponseHeaderTimeout = c.responseHeaderTimeout
+	}
+	if c.tlsHandshakeTimeout > 0 {
+		tr.TLSHandshakeTimeout = c.tlsHandshakeTimeout
+	}
+	if c.needsDialer() {
+		// 需要定制拨号参数时使用新的 Dialer, 原 Transport 上自定义的 DialContext 不再生效
+		tr.DialContext = c.dialer().DialContext
+	}
+	enableWarmDial(tr)
+	if c.maxConnRequests > 0 || c.maxConnAge > 0 {
+		if tr.DialContext == nil {
+			tr.DialContext = c.dialer().DialContext
+		}
+		tr.DialContext = countRequestsDial(tr.DialContext, c.maxConnAge)
+		disableHTTP2(tr)
+	}
+}
+
+// needsDialer 是否设置了需要定制 Dialer 的选项
+func (c transportConfig) needsDialer() bool {
+	return c.dialTimeout > 0 || c.localAddr != "" || c.happyEyeballs
+}
+
+// dialer 按配置创建 Dialer, 未设置的参数与 GetHttpClient 的默认值保持一致
+func (c transportConfig) dialer() *net.Dialer {
+	d := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if c.dialTimeout > 0 {
+		d.Timeout = c.dialTimeout
+	}
+	if c.localAddr != "" {
+		d.LocalAddr = &net.TCPAddr{IP: net.ParseIP(c.localAddr)}
+	}
+	if c.happyEyeballs {
+		d.FallbackDelay = happyEyeballsDelay
+	}
+	return d
+}
+
+type derivedTransportKey struct {
+	base   *http.Transport
+	config transportConfig
+}
+
+// maxDerivedTransports 派生 Transport 缓存的上限, 每个派生 Transport 都有自己的连接池
+// 选项取值很分散(如按请求计算的超时)或不断使用新的 WithClient 时, 超过上限淘汰最久没有使用的
+const maxDerivedTransports = 64
+
+// derivedTransports 缓存派生出的 Transport, 配置相同的请求共用同一个连接池
+var derivedTransports = &transportCache{entries: map[derivedTransportKey]*transportCacheEntry{}}
+
+type transportCacheEntry struct {
+	transport *http.Transport
+	lastUsed  uint64 // 最近一次使用的序号, 用于淘汰最久没有使用的
+}
+
+// transportCache 有上限的派生 Transport 缓存, 被移除的 Transport 会关闭空闲连接
+// 正在进行的请求不受影响, 它们用完的连接在空闲超时后关闭
+type transportCache struct {
+	mu      sync.Mutex
+	entries map[derivedTransportKey]*transportCacheEntry
+	clock   uint64
+}
+
+// get 返回 key 对应的派生 Transport, 没有时用 derive 创建
+func (c *transportCache) get(key derivedTransportKey, derive func() *http.Transport) *http.Transport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clock++
+	if e, ok := c.entries[key]; ok {
+		e.lastUsed = c.clock
+		return e.transport
+	}
+	if len(c.entries) >= maxDerivedTransports {
+		c.evictOldest()
+	}
+	tr := derive()
+	c.entries[key] = &transportCacheEntry{transport: tr, lastUsed: c.clock}
+	return tr
+}
+
+func (c *transportCache) evictOldest() {
+	var oldest derivedTransportKey
+	var oldestUsed uint64
+	for key, e := range c.entries {
+		if oldestUsed == 0 || e.lastUsed < oldestUsed {
+			oldest, oldestUsed = key, e.lastUsed
+		}
+	}
+	c.entries[oldest].transport.CloseIdleConnections()
+	forgetTransport(c.entries[oldest].transport)
+	delete(c.entries, oldest)
+}
+
+// removeBase 移除从 base 派生的 Transport, base 为 nil 时全部移除
+func (c *transportCache) removeBase(base *http.Transport) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, e := range c.entries {
+		if base == nil || key.base == base {
+			e.transport.CloseIdleConnections()
+			forgetTransport(e.transport)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// len 缓存中的派生 Transport 数量
+func (c *transportCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// WithClient 本次请求使用指定的客户端, 不使用也不修改全局客户端, 用于按请求使用不同的代理、TLS 证书等配置, 如多租户服务按租户使用不同的客户端证书
+// 需要派生 Transport 的选项会基于 c 的 Transport 派生; c 为 nil 时使用全局客户端
+func WithClient(c *http.Client) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.client = c
+		return
+	})
+}
+
+// httpClient 返回本次请求使用的客户端
+// 没有设置 Transport 相关选项时直接使用全局客户端(或会话的客户端), 否则基于它的 Transport 派生
+func (opts *requestOption) httpClient() (*http.Client, error) {
+	c := opts.client
+	if c == nil {
+		c = GetHttpClient()
+	}
+	if opts.transport == (transportConfig{}) {
+		return c, nil
+	}
+
+	var base *http.Transport
+	switch tr := c.Transport.(type) {
+	case nil:
+		base = http.DefaultTransport.(*http.Transport)
+	case *http.Transport:
+		base = tr
+	default:
+		return nil, fmt.Errorf("transport options require an *http.Transport, got %T", c.Transport)
+	}
+
+	key := derivedTransportKey{base: base, config: opts.transport}
+	tr := derivedTransports.get(key, func() *http.Transport {
+		derived := cloneTransport(base)
+		opts.transport.apply(derived)
+		return derived
+	})
+	derivedClient := *c
+	derivedClient.Transport = tr
+	return &derivedClient, nil
+}
+
+// WithResponseTimeout 设置等待响应头的超时时间, 即服务端处理请求的耗时上限
+// 与 WithTimeout 不同, 它不限制响应体的传输时间, 用于区分"服务端卡住"和"下载大文件"
+func WithResponseTimeout(timeout time.Duration) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.transport.responseHeaderTimeout, err = timeout, nil
+		return
+	})
+}
+
+// WithConnectTimeout 设置建立TCP连接的超时时间, 默认的 30 秒对快速切换备用地址来说太长
+// 与 WithTimeout 相互独立, 如连接 500ms 建不上就切换到 WithFallbackURLs 的备用地址, 同时允许整个请求耗时 10 秒
+func WithConnectTimeout(timeout time.Duration) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.transport.dialTimeout, err = timeout, nil
+		return
+	})
+}
+
+// WithLocalAddr 指定发起连接使用的本地IP, 用于多网卡机器或出口防火墙按源IP放行的场景
+// addr 必须是本机网卡上的IP, 否则返回错误
+func WithLocalAddr(addr string) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return fmt.Errorf("invalid local address %q", addr)
+		}
+		addrs, err := net.InterfaceAddrs()
+		if err != nil {
+			return err
+		}
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				opts.transport.localAddr = ip.String()
+				return nil
+			}
+		}
+		return fmt.Errorf("local address %q is not assigned to any interface", addr)
+	})
+}
+
+// happyEyeballsDelay RFC 8305 建议的首选地址族连接尝试与备选地址族之间的间隔
+const happyEyeballsDelay = 250 * time.Millisecond
+
+// WithHappyEyeballs 在派生的 Dialer 上开启 Happy Eyeballs(RFC 8305): host 同时解析出 IPv6 和 IPv4 地址时,
+// 先连接首选地址族, 250ms 内没有连上就并行连接另一个地址族, 使用先连上的连接并立即取消另一个
+// 用于双栈网络中某个地址族很慢或不通的情况. 连接竞速由 net.Dialer 实现, 默认的 Dialer 也会以 300ms 的间隔竞速,
+// 这个选项保证派生的 Transport 使用 RFC 建议的间隔, 且不受原 Transport 上自定义 DialContext 的影响
+func WithHappyEyeballs() Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.transport.happyEyeballs = true
+		return
+	})
+}
+
+// PhaseTimeouts 分阶段的超时时间, 为0的阶段使用默认值
+type PhaseTimeouts struct {
+	Dial           time.Duration // 建立TCP连接
+	TLS            time.Duration // TLS握手
+	ResponseHeader time.Duration // 发送请求后等待响应头
+	Total          time.Duration // 整个请求, 等同于 WithTimeout
+}
+
+// WithPhaseTimeouts 一次性设置各阶段的超时时间
+// 某个阶段或连接阶段之和超过 Total 时该阶段的超时实际不会生效, 请求时会记一条 Warn 日志提示配置不合理
+func WithPhaseTimeouts(timeouts PhaseTimeouts) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.transport.dialTimeout = timeouts.Dial
+		opts.transport.tlsHandshakeTimeout = timeouts.TLS
+		opts.transport.responseHeaderTimeout = timeouts.ResponseHeader
+		if timeouts.Total > 0 {
+			opts.timeout, opts.timeoutSet = timeouts.Total, true
+		}
+		opts.phaseTimeouts = &timeouts
+		return
+	})
+}
+
+// checkPhaseTimeouts 检查分阶段超时是否超过总超时
+func (opts *requestOption) checkPhaseTimeouts(ctx context.Context) {
+	t := opts.phaseTimeouts
+	if t == nil || t.Total <= 0 {
+		return
+	}
+	phases := []struct {
+		name    string
+		timeout time.Duration
+	}{{"dial", t.Dial}, {"tls", t.TLS}, {"response_header", t.ResponseHeader}, {"dial+tls+response_header", t.Dial + t.TLS + t.ResponseHeader}}
+	for _, phase := range phases {
+		if phase.timeout > t.Total {
+			opts.logger.Warn(ctx, "HTTP_PHASE_TIMEOUT_EXCEEDS_TOTAL", opts.withLoggerFields("phase", phase.name, "timeout", phase.timeout, "total", t.Total)...)
+		}
+	}
+}
+
+// longPollGrace 长轮询时在 maxWait 之外留给网络传输和服务端处理的余量
+const longPollGrace = 5 * time.Second
+
+// WithLongPoll 用于长轮询接口: 服务端最多挂起 maxWait 才返回, 等待响应头的超时放宽到 maxWait 加上余量
+// 总超时小于它时也会被放宽, 避免被默认的短超时打断; 长时间等待是预期行为, 不再输出慢请求日志
+func WithLongPoll(maxWait time.Duration) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		if maxWait <= 0 {
+			return fmt.Errorf("long poll max wait must be positive, got %s", maxWait)
+		}
+		opts.longPoll = maxWait
+		opts.transport.responseHeaderTimeout = maxWait + longPollGrace
+		return
+	})
+}
+
+// extendLongPollTimeout 在全部选项应用完之后保证总超时不小于长轮询的等待时间
+func (opts *requestOption) extendLongPollTimeout() {
+	if opts.longPoll > 0 && opts.timeout < opts.longPoll+longPollGrace {
+		opts.timeout = opts.longPoll + longPollGrace
+	}
+}
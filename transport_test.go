@@ -0,0 +1,279 @@
+package httptool
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWithResponseTimeout 测试等待响应头超时
+func TestWithResponseTimeout(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/hang":
+			time.Sleep(200 * time.Millisecond) // 模拟服务端迟迟不返回响应头
+			w.WriteHeader(http.StatusOK)
+		case "/stream":
+			// 响应头立即返回, 响应体缓慢传输
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte(`{"status":"ok"}`))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	t.Run("响应头超时", func(t *testing.T) {
+		_, _, err := Get(ctx, server.URL+"/hang", WithResponseTimeout(50*time.Millisecond))
+		if err == nil || !strings.Contains(err.Error(), "timeout awaiting response headers") {
+			t.Fatalf("期望响应头超时错误, 得到 %v", err)
+		}
+	})
+
+	t.Run("响应体慢不受影响", func(t *testing.T) {
+		_, body, err := Get(ctx, server.URL+"/stream", WithResponseTimeout(50*time.Millisecond))
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		if string(body) != `{"status":"ok"}` {
+			t.Fatalf("期望响应体 %s, 得到 %s", `{"status":"ok"}`, string(body))
+		}
+	})
+}
+
+// TestDerivedTransportCache 测试相同配置的请求共用派生的 Transport
+func TestDerivedTransportCache(t *testing.T) {
+	ResetDefaultClient()
+	defer ResetDefaultClient()
+
+	opts1, opts2 := defaultRequestOptions(), defaultRequestOptions()
+	WithResponseTimeout(time.Second).apply(opts1)
+	WithResponseTimeout(time.Second).apply(opts2)
+
+	c1, err := opts1.httpClient()
+	if err != nil {
+		t.Fatalf("派生客户端失败: %v", err)
+	}
+	c2, err := opts2.httpClient()
+	if err != nil {
+		t.Fatalf("派生客户端失败: %v", err)
+	}
+	if c1.Transport != c2.Transport {
+		t.Fatal("相同配置应共用同一个派生 Transport")
+	}
+	if c1.Transport == GetHttpClient().Transport {
+		t.Fatal("设置了 Transport 选项时不应直接使用全局 Transport")
+	}
+
+	// 自定义客户端不是 *http.Transport 时返回错误
+	SetHttpClient(&http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) { return nil, nil })})
+	if _, err := opts1.httpClient(); err == nil {
+		t.Fatal("非 *http.Transport 时应返回错误")
+	}
+}
+
+// TestDerivedTransportEviction 测试派生 Transport 缓存有上限, 更换全局客户端时移除从原 Transport 派生的
+func TestDerivedTransportEviction(t *testing.T) {
+	ResetDefaultClient()
+	defer ResetDefaultClient()
+
+	for i := 1; i <= maxDerivedTransports+50; i++ {
+		opts := defaultRequestOptions()
+		WithConnectTimeout(time.Duration(i) * time.Millisecond).apply(opts)
+		if _, err := opts.httpClient(); err != nil {
+			t.Fatalf("派生客户端失败: %v", err)
+		}
+	}
+	if n := derivedTransports.len(); n != maxDerivedTransports {
+		t.Fatalf("缓存应淘汰到上限 %d, 得到 %d", maxDerivedTransports, n)
+	}
+
+	// 最近使用过的保留, 最久没有使用的被淘汰
+	recent := defaultRequestOptions()
+	WithConnectTimeout(time.Duration(maxDerivedTransports+50) * time.Millisecond).apply(recent)
+	c1, _ := recent.httpClient()
+	c2, _ := recent.httpClient()
+	if c1.Transport != c2.Transport {
+		t.Fatal("最近使用的派生 Transport 不应被淘汰")
+	}
+
+	// 只换客户端不换 Transport 时保留派生的 Transport
+	SetHttpClient(&http.Client{Transport: GetHttpClient().Transport, Timeout: time.Second})
+	if n := derivedTransports.len(); n != maxDerivedTransports {
+		t.Fatalf("Transport 没有变化时不应移除派生 Transport, 剩余 %d", n)
+	}
+
+	SetHttpClient(&http.Client{Transport: &http.Transport{}})
+	if n := derivedTransports.len(); n != 0 {
+		t.Fatalf("更换 Transport 后应移除从原 Transport 派生的, 剩余 %d", n)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// TestWithPhaseTimeouts 测试分阶段超时
+func TestWithPhaseTimeouts(t *testing.T) {
+	ResetDefaultClient()
+
+	opts := defaultRequestOptions()
+	mockLogger := &MockLogger{}
+	WithLogger(mockLogger).apply(opts)
+	WithPhaseTimeouts(PhaseTimeouts{
+		Dial:           time.Second,
+		TLS:            2 * time.Second,
+		ResponseHeader: 3 * time.Second,
+		Total:          5 * time.Second,
+	}).apply(opts)
+	if opts.timeout != 5*time.Second {
+		t.Fatalf("期望总超时 %v, 得到 %v", 5*time.Second, opts.timeout)
+	}
+
+	c, err := opts.httpClient()
+	if err != nil {
+		t.Fatalf("派生客户端失败: %v", err)
+	}
+	tr := c.Transport.(*http.Transport)
+	if tr.TLSHandshakeTimeout != 2*time.Second || tr.ResponseHeaderTimeout != 3*time.Second {
+		t.Fatalf("分阶段超时未生效, TLS %v, ResponseHeader %v", tr.TLSHandshakeTimeout, tr.ResponseHeaderTimeout)
+	}
+
+	// 各阶段之和超过总超时时记 Warn 日志
+	opts.checkPhaseTimeouts(context.Background())
+	if !mockLogger.warnCalled {
+		t.Fatal("阶段超时之和超过总超时应记录 Warn 日志")
+	}
+}
+
+// TestWithConnectTimeout 测试连接超时与总超时相互独立
+func TestWithConnectTimeout(t *testing.T) {
+	ResetDefaultClient()
+
+	opts := defaultRequestOptions()
+	WithTimeout(10 * time.Second).apply(opts)
+	WithConnectTimeout(500 * time.Millisecond).apply(opts)
+	if opts.timeout != 10*time.Second {
+		t.Fatalf("连接超时不应影响总超时, 得到 %v", opts.timeout)
+	}
+	if d := opts.transport.dialer(); d.Timeout != 500*time.Millisecond {
+		t.Fatalf("期望连接超时 500ms, 得到 %v", d.Timeout)
+	}
+	c, err := opts.httpClient()
+	if err != nil {
+		t.Fatalf("派生客户端失败: %v", err)
+	}
+	if c.Transport == GetHttpClient().Transport {
+		t.Fatal("连接超时应在派生 Transport 上生效")
+	}
+}
+
+// TestWithLocalAddr 测试指定本地IP发起连接
+func TestWithLocalAddr(t *testing.T) {
+	ResetDefaultClient()
+
+	var remoteIP string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteIP, _, _ = net.SplitHostPort(r.RemoteAddr)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	if _, _, err := Get(ctx, server.URL, WithLocalAddr("127.0.0.1")); err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if remoteIP != "127.0.0.1" {
+		t.Fatalf("期望源IP %s, 得到 %s", "127.0.0.1", remoteIP)
+	}
+
+	for _, addr := range []string{"not-an-ip", "192.0.2.1"} {
+		if _, _, err := Get(ctx, server.URL, WithLocalAddr(addr)); err == nil {
+			t.Fatalf("非本机IP %s 应返回错误", addr)
+		}
+	}
+}
+
+// TestWithHappyEyeballs 测试派生的 Dialer 开启 Happy Eyeballs
+func TestWithHappyEyeballs(t *testing.T) {
+	ResetDefaultClient()
+
+	opts := defaultRequestOptions()
+	WithHappyEyeballs().apply(opts)
+	if d := opts.transport.dialer(); d.FallbackDelay != happyEyeballsDelay {
+		t.Fatalf("期望备选地址族延迟 %v, 得到 %v", happyEyeballsDelay, d.FallbackDelay)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	// localhost 可能同时解析出 ::1 和 127.0.0.1, 服务端只监听了 IPv4
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	if _, body, err := Get(context.Background(), "http://localhost:"+port, WithHappyEyeballs()); err != nil || string(body) != "ok" {
+		t.Fatalf("请求失败: %q %v", string(body), err)
+	}
+}
+
+// TestWithClient 测试按请求使用指定的客户端
+func TestWithClient(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	// 全局客户端不信任测试服务器的证书
+	if _, _, err := Get(ctx, server.URL); err == nil {
+		t.Fatal("全局客户端请求测试服务器应该失败")
+	}
+	if _, body, err := Get(ctx, server.URL, WithClient(server.Client())); err != nil || string(body) != "ok" {
+		t.Fatalf("使用指定的客户端请求失败: %q %v", string(body), err)
+	}
+	if GetHttpClient() == server.Client() {
+		t.Fatal("WithClient 不应修改全局客户端")
+	}
+	// 需要派生 Transport 的选项基于指定客户端的 Transport 派生
+	if _, _, err := Get(ctx, server.URL, WithClient(server.Client()), WithResponseTimeout(time.Second)); err != nil {
+		t.Fatalf("派生 Transport 后请求失败: %v", err)
+	}
+}
+
+// TestWithLongPoll 测试长轮询放宽超时且不记慢请求日志
+func TestWithLongPoll(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond) // 模拟服务端挂起等待事件
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	logger := &MockLogger{}
+	// 总超时和等待响应头的超时都比服务端挂起的时间短, 长轮询会放宽它们
+	_, _, err := Get(context.Background(), server.URL,
+		WithTimeout(50*time.Millisecond), WithResponseTimeout(50*time.Millisecond),
+		WithSlowThreshold(10*time.Millisecond), WithLogger(logger), WithLongPoll(time.Second))
+	if err != nil {
+		t.Fatalf("长轮询请求不应超时: %v", err)
+	}
+	if logger.warnCalled {
+		t.Fatalf("长轮询不应记慢请求日志, 得到 %s", logger.lastMsg)
+	}
+
+	if _, _, err := Get(context.Background(), server.URL, WithLongPoll(0)); err == nil {
+		t.Fatal("maxWait 为 0 时期望返回错误")
+	}
+}
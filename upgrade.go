@@ -0,0 +1,118 @@
+package httptool
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"strings"
+	"time"
+)
+
+// websocketGUID RFC 6455 中用于计算 Sec-WebSocket-Accept 的固定GUID
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Upgrade 执行 WebSocket 的 HTTP Upgrade 握手, 返回握手完成后的连接, 由调用方在上面驱动 WebSocket 协议
+// 握手同样应用请求头(包括 WithBearerToken、WithBasicAuth、WithRawHeader)、WithRequireHeaders 校验和日志等选项, WithTimeout 只限制握手阶段; 调用方负责关闭返回的连接
+// 自定义客户端设置了 http.Client.Timeout 时, 超时后连接会被关闭, 长连接场景请不要设置
+func Upgrade(ctx context.Context, url string, options ...Option) (conn net.Conn, resp *http.Response, err error) {
+	start := time.Now()
+	reqOpts := defaultRequestOptions()
+	options = append(slices.Clip(options), WithContext(ctx))
+	for _, opt := range options {
+		err = opt.apply(reqOpts)
+		if err != nil {
+			return
+		}
+	}
+	reqOpts.applyAuthorization()
+	if err = reqOpts.checkRequest(); err != nil {
+		return
+	}
+
+	nonce := make([]byte, 16)
+	if _, err = rand.Read(nonce); err != nil {
+		return
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	handshakeCtx, cancel := context.WithTimeout(reqOpts.ctx, reqOpts.timeout)
+	defer cancel()
+	var rawConn net.Conn
+	handshakeCtx = httptrace.WithClientTrace(handshakeCtx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) { rawConn = info.Conn },
+	})
+	req, err := http.NewRequestWithContext(handshakeCtx, "GET", url, nil)
+	if err != nil {
+		return
+	}
+	reqOpts.setHeaders(req)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+
+	defer func() {
+		reqOpts.logger.Debug(reqOpts.ctx, "HTTP_UPGRADE_DEBUG_LOG", reqOpts.withLoggerFields("url", url, "err", err, "dur/ms", time.Since(start))...)
+	}()
+
+	client, err := reqOpts.httpClient()
+	if err != nil {
+		return
+	}
+	resp, err = client.Do(req)
+	if err != nil {
+		return
+	}
+	if err = checkUpgradeResponse(resp, key); err != nil {
+		resp.Body.Close()
+		return nil, resp, err
+	}
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok || rawConn == nil {
+		resp.Body.Close()
+		return nil, resp, errors.New("upgrade response body is not writable")
+	}
+	return &upgradedConn{Conn: rawConn, rwc: rwc}, resp, nil
+}
+
+// checkUpgradeResponse 校验握手响应
+func checkUpgradeResponse(resp *http.Response, key string) error {
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return fmt.Errorf("upgrade failed, response code: %d", resp.StatusCode)
+	}
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") {
+		return fmt.Errorf("upgrade failed, unexpected Upgrade header %q", resp.Header.Get("Upgrade"))
+	}
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		return errors.New("upgrade failed, invalid Sec-WebSocket-Accept")
+	}
+	return nil
+}
+
+// upgradedConn 握手完成后交给调用方的连接
+// 读写通过 Transport 返回的响应体进行, 其中可能缓冲了握手响应之后服务端已发送的数据; 地址和超时设置使用底层连接
+type upgradedConn struct {
+	net.Conn
+	rwc io.ReadWriteCloser
+}
+
+func (c *upgradedConn) Read(p []byte) (int, error) {
+	return c.rwc.Read(p)
+}
+
+func (c *upgradedConn) Write(p []byte) (int, error) {
+	return c.rwc.Write(p)
+}
+
+func (c *upgradedConn) Close() error {
+	return c.rwc.Close()
+}
@@ -0,0 +1,83 @@
+package httptool
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestUpgrade 测试 WebSocket 握手并在返回的连接上读写
+func TestUpgrade(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" || r.Header.Get("Authorization") != "Bearer token123" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + websocketGUID))
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+		rw.Flush()
+		// 回显收到的一行数据
+		line, _ := rw.ReadString('\n')
+		rw.WriteString("echo: " + line)
+		rw.Flush()
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	conn, resp, err := Upgrade(ctx, server.URL, WithTimeout(50*time.Millisecond),
+		WithHeaders(map[string]string{"Authorization": "Bearer token123"}))
+	if err != nil {
+		t.Fatalf("握手失败: %v", err)
+	}
+	defer conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusSwitchingProtocols, resp.StatusCode)
+	}
+
+	// 超过握手超时时间后连接仍然可用
+	time.Sleep(100 * time.Millisecond)
+	if _, err := io.WriteString(conn, "hello\n"); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("读取失败: %v", err)
+	}
+	if line != "echo: hello\n" {
+		t.Fatalf("期望 %q, 得到 %q", "echo: hello\n", line)
+	}
+
+	// 握手被拒绝时返回错误和响应
+	_, resp, err = Upgrade(ctx, server.URL)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("期望握手失败, 得到 %v", err)
+	}
+
+	// WithBearerToken 与普通请求一样设置 Authorization 请求头
+	conn, _, err = Upgrade(ctx, server.URL, WithBearerToken("token123"))
+	if err != nil {
+		t.Fatalf("使用 WithBearerToken 握手失败: %v", err)
+	}
+	conn.Close()
+
+	// 缺少 WithRequireHeaders 要求的请求头时不发起握手
+	if _, resp, err = Upgrade(ctx, server.URL, WithBearerToken("token123"), WithRequireHeaders("X-Tenant")); !errors.Is(err, ErrMissingRequiredHeader) || resp != nil {
+		t.Fatalf("期望 ErrMissingRequiredHeader, 得到 %v", err)
+	}
+}
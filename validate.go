@@ -0,0 +1,192 @@
+package httptool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ErrUnexpectedContentType 响应的 Content-Type 与 WithExpectContentType 设置的不一致时返回
+var ErrUnexpectedContentType = errors.New("unexpected response Content-Type")
+
+// ErrMissingRequiredHeader 缺少 WithRequireHeaders 要求的请求头时返回
+var ErrMissingRequiredHeader = errors.New("missing required request header")
+
+// WithRequireHeaders 发送前检查这些请求头已经被其他选项设置(不区分大小写), 缺少时不发送请求直接返回错误
+// 用于在客户端封装中强制要求 API Key 之类的必填请求头, 避免拿到服务端含糊的 401
+func WithRequireHeaders(keys ...string) Option {
+	keys = slices.Clone(keys)
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.requiredHeaders = append(opts.requiredHeaders, keys...)
+		return
+	})
+}
+
+// checkRequest 在全部选项应用完之后检查请求配置
+func (opts *requestOption) checkRequest() error {
+	var missing []string
+	for _, key := range opts.requiredHeaders {
+		if headerValue(opts.headers, key) == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingRequiredHeader, strings.Join(missing, ", "))
+	}
+	if opts.validateJSON && isJSONContentType(headerValue(opts.headers, "Content-Type")) && !json.Valid(opts.data) {
+		return ErrInvalidJSONBody
+	}
+	return nil
+}
+
+// WithExpectContentType 校验响应的 Content-Type, 两边都忽略 charset 等参数, 只按媒体类型的前缀匹配
+// 用于拦截接口返回 200 但响应体是 HTML 错误页之类的情况, 校验失败时仍会返回读到的响应体
+func WithExpectContentType(contentType string) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.expectContentType, err = contentType, nil
+		return
+	})
+}
+
+// expectedMediaType 取 WithExpectContentType 期望值中的媒体类型, 忽略 charset 等参数以及其中的空格、顺序;
+// 期望值只是前缀(如 "application/")、不能按媒体类型解析时, 去掉参数后按原样小写
+func expectedMediaType(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
+
+// ResponseValidator 校验读取完成的响应, 返回的错误作为请求的错误返回
+type ResponseValidator func(httpStatusCode int, header http.Header, respBody []byte) error
+
+// WithResponseValidator 添加响应校验函数, 多个校验函数(包括多次设置的)按添加顺序组成一条链依次执行, 第一个返回错误的校验函数之后的不再执行
+// 校验在 WithExpectContentType 和 WithErrorHeader 之后执行, 校验失败时仍会返回读到的响应体
+// 常用的校验组合可以保存为一个 Option 在多个请求间复用
+func WithResponseValidator(validators ...ResponseValidator) Option {
+	validators = slices.Clone(validators)
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.responseValidators = append(opts.responseValidators, validators...)
+		return
+	})
+}
+
+// ErrTooManyHeaders 开启 WithMaxResponseHeaderCount 后响应头的字段数超过上限时返回
+var ErrTooManyHeaders = errors.New("too many response header fields")
+
+// WithMaxResponseHeaderCount 限制响应头的字段数(同名响应头的多个值分别计数), 超过时不读取响应体并返回 ErrTooManyHeaders
+// 与 Transport 的 MaxResponseHeaderBytes 按字节数限制互补, 用于防范不可信的服务端用大量很小的响应头消耗资源
+func WithMaxResponseHeaderCount(n int) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		if n <= 0 {
+			return fmt.Errorf("max response header count must be positive, got %d", n)
+		}
+		opts.maxResponseHeaderCount = n
+		return
+	})
+}
+
+// checkHeaderCount 收到响应后检查响应头的字段数
+func (opts *requestOption) checkHeaderCount(header http.Header) error {
+	if opts.maxResponseHeaderCount <= 0 {
+		return nil
+	}
+	count := 0
+	for _, values := range header {
+		count += len(values)
+	}
+	if count > opts.maxResponseHeaderCount {
+		return fmt.Errorf("%w: %d fields exceeds limit %d", ErrTooManyHeaders, count, opts.maxResponseHeaderCount)
+	}
+	return nil
+}
+
+// WithOnResponseHeaders 收到响应头后、读取响应体之前调用 fn, 可以根据状态码、Content-Type、Content-Length 等决定是否读取响应体
+// fn 返回错误时请求返回该错误; proceed 为 false 时不读取响应体直接关闭连接, 返回的响应体为 nil, 用于尽早放弃不需要的大响应以节省带宽
+// 放弃读取时状态码不表示成功仍然返回 *HTTPStatusError(Body 为 nil); 重试、对冲请求的每次尝试都会调用 fn
+func WithOnResponseHeaders(fn func(httpStatusCode int, header http.Header) (proceed bool, err error)) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.onResponseHeaders = fn
+		return
+	})
+}
+
+// WithExpectedStatus 指定哪些状态码视为成功, 替代默认的 2xx, 其他状态码返回 *HTTPStatusError
+// 如只接受 200, 或关闭跟随重定向后把 3xx 视为成功; 多次设置时后设置的生效
+func WithExpectedStatus(codes ...int) Option {
+	expected := make(map[int]bool, len(codes))
+	for _, code := range codes {
+		expected[code] = true
+	}
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.expectedStatus = expected
+		return
+	})
+}
+
+// isSuccessStatus 状态码是否表示请求成功, 设置了 WithExpectedStatus 时只认指定的状态码
+func (opts *requestOption) isSuccessStatus(httpStatusCode int) bool {
+	if opts.expectedStatus != nil {
+		return opts.expectedStatus[httpStatusCode]
+	}
+	return httpStatusCode >= 200 && httpStatusCode < 300
+}
+
+// WithStatusHandler 为指定的状态码注册处理函数, 如 429 时解析限流响应头、401 时触发重新认证
+// 收到该状态码时读取响应体后调用 fn, fn 的返回值作为请求的错误(返回nil表示请求成功), 替代默认的非 2xx 错误
+// 同一个状态码多次注册时后注册的生效; 处理函数在 WithResponseValidator 之前执行
+func WithStatusHandler(code int, fn func(respBody []byte, header http.Header) error) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		if opts.statusHandlers == nil {
+			opts.statusHandlers = map[int]func(respBody []byte, header http.Header) error{}
+		}
+		opts.statusHandlers[code] = fn
+		return
+	})
+}
+
+// ResponseHeaderError 响应头中带有 WithErrorHeader 指定的错误标记
+type ResponseHeaderError struct {
+	Header string // 响应头名称
+	Value  string // 响应头的值, 一般是错误码
+}
+
+func (e *ResponseHeaderError) Error() string {
+	return fmt.Sprintf("error signaled by response header %s: %s", e.Header, e.Value)
+}
+
+// WithErrorHeader 响应中 headerName 响应头存在且不为空时返回 *ResponseHeaderError, 即使状态码是 200
+// 用于状态码永远是 200、通过 X-Error-Code 这类响应头表示失败的老接口; 检查失败时仍会返回读到的响应体
+func WithErrorHeader(headerName string) Option {
+	return optionFunc(func(opts *requestOption) (err error) {
+		opts.errorHeader, err = headerName, nil
+		return
+	})
+}
+
+// checkResponse 对读取完成的响应做选项要求的校验
+func (opts *requestOption) checkResponse(httpStatusCode int, header http.Header, respBody []byte) error {
+	if opts.expectContentType != "" {
+		contentType := header.Get("Content-Type")
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || !strings.HasPrefix(mediaType, expectedMediaType(opts.expectContentType)) {
+			return fmt.Errorf("%w: got %q, want %q", ErrUnexpectedContentType, contentType, opts.expectContentType)
+		}
+	}
+	if opts.errorHeader != "" {
+		if value := header.Get(opts.errorHeader); value != "" {
+			return &ResponseHeaderError{Header: opts.errorHeader, Value: value}
+		}
+	}
+	for _, validate := range opts.responseValidators {
+		if err := validate(httpStatusCode, header, respBody); err != nil {
+			return err
+		}
+	}
+	return nil
+}
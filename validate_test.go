@@ -0,0 +1,309 @@
+package httptool
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestWithExpectContentType 测试响应 Content-Type 校验
+func TestWithExpectContentType(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write([]byte(`{"status":"ok"}`))
+		case "/json-compact":
+			w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+			w.Write([]byte(`{"status":"ok"}`))
+		case "/html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<html>error</html>`))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	t.Run("匹配", func(t *testing.T) {
+		_, _, err := Get(ctx, server.URL+"/json", WithExpectContentType("application/json"))
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+	})
+
+	t.Run("期望值带参数", func(t *testing.T) {
+		// 只比较媒体类型, 参数的写法、空格和大小写不同也匹配
+		for _, path := range []string{"/json", "/json-compact"} {
+			for _, expected := range []string{"application/json; charset=utf-8", "Application/JSON;charset=gbk", "application/"} {
+				if _, _, err := Get(ctx, server.URL+path, WithExpectContentType(expected)); err != nil {
+					t.Fatalf("%s 期望 %q 匹配, 得到 %v", path, expected, err)
+				}
+			}
+		}
+		if _, _, err := Get(ctx, server.URL+"/html", WithExpectContentType("application/json; charset=utf-8")); !errors.Is(err, ErrUnexpectedContentType) {
+			t.Fatalf("期望错误 %v, 得到 %v", ErrUnexpectedContentType, err)
+		}
+	})
+
+	t.Run("不匹配", func(t *testing.T) {
+		_, body, err := Get(ctx, server.URL+"/html", WithExpectContentType("application/json"))
+		if !errors.Is(err, ErrUnexpectedContentType) {
+			t.Fatalf("期望错误 %v, 得到 %v", ErrUnexpectedContentType, err)
+		}
+		if string(body) != `<html>error</html>` {
+			t.Fatalf("校验失败时应返回响应体, 得到 %s", string(body))
+		}
+	})
+}
+
+// TestWithRequireHeaders 测试必填请求头检查
+func TestWithRequireHeaders(t *testing.T) {
+	ResetDefaultClient()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	require := WithRequireHeaders("X-Api-Key", "X-Tenant")
+
+	_, _, err := Get(ctx, server.URL, require, WithHeaders(map[string]string{"x-api-key": "secret"}))
+	if !errors.Is(err, ErrMissingRequiredHeader) || !strings.Contains(err.Error(), "X-Tenant") {
+		t.Fatalf("期望缺少 X-Tenant 的错误, 得到 %v", err)
+	}
+	if requests != 0 {
+		t.Fatal("缺少必填请求头时不应发送请求")
+	}
+
+	// 必填请求头可以由任意位置的选项设置
+	_, _, err = Get(ctx, server.URL, require, WithHeaders(map[string]string{"x-api-key": "secret", "X-Tenant": "t1"}))
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+}
+
+// TestWithResponseValidator 测试响应校验链
+func TestWithResponseValidator(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"code":1}`))
+	}))
+	defer server.Close()
+
+	var calls []string
+	record := func(name string, err error) ResponseValidator {
+		return func(httpStatusCode int, header http.Header, respBody []byte) error {
+			calls = append(calls, name)
+			return err
+		}
+	}
+	errBusiness := errors.New("business error")
+	common := WithResponseValidator(record("status", nil), record("content-type", nil))
+
+	_, body, err := Get(context.Background(), server.URL, common, WithResponseValidator(record("code", errBusiness), record("never", nil)))
+	if !errors.Is(err, errBusiness) {
+		t.Fatalf("期望返回校验函数的错误, 得到 %v", err)
+	}
+	if strings.Join(calls, ",") != "status,content-type,code" {
+		t.Fatalf("校验函数应按顺序执行且在第一个错误处停止, 实际执行 %v", calls)
+	}
+	if string(body) != `{"code":1}` {
+		t.Fatalf("校验失败时仍应返回响应体, 得到 %q", string(body))
+	}
+}
+
+// TestWithStatusHandler 测试按状态码处理响应
+func TestWithStatusHandler(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/limited":
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte("slow down"))
+		case "/accepted":
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte("queued"))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	errRateLimited := errors.New("rate limited")
+	var retryAfter, gotBody string
+	onLimited := WithStatusHandler(http.StatusTooManyRequests, func(respBody []byte, header http.Header) error {
+		retryAfter, gotBody = header.Get("Retry-After"), string(respBody)
+		return errRateLimited
+	})
+
+	status, _, err := Get(ctx, server.URL+"/limited", onLimited)
+	if !errors.Is(err, errRateLimited) || status != http.StatusTooManyRequests {
+		t.Fatalf("期望处理函数返回的错误, 得到 %d %v", status, err)
+	}
+	if retryAfter != "3" || gotBody != "slow down" {
+		t.Fatalf("处理函数应拿到响应头和响应体, 得到 %q %q", retryAfter, gotBody)
+	}
+
+	// 处理函数返回nil时请求成功
+	_, body, err := Get(ctx, server.URL+"/accepted", WithStatusHandler(http.StatusAccepted, func([]byte, http.Header) error { return nil }))
+	if err != nil || string(body) != "queued" {
+		t.Fatalf("期望请求成功并返回响应体, 得到 %q %v", string(body), err)
+	}
+
+	// 没有注册处理函数的状态码仍然返回默认错误
+	if _, _, err := Get(ctx, server.URL+"/error", onLimited); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("期望默认的状态码错误, 得到 %v", err)
+	}
+}
+
+// TestWithOnResponseHeaders 测试读取响应体前根据响应头决定是否继续
+func TestWithOnResponseHeaders(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		w.WriteHeader(http.StatusOK)
+		chunk := []byte(strings.Repeat("x", 32<<10))
+		for i := 0; i < 64; i++ { // 2MB, 客户端放弃读取后写入会失败
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	onlyJSON := WithOnResponseHeaders(func(status int, header http.Header) (bool, error) {
+		return header.Get("Content-Type") == "application/json", nil
+	})
+
+	status, body, err := Get(ctx, server.URL+"?type=application/json", onlyJSON)
+	if err != nil || status != http.StatusOK || len(body) != 2<<20 {
+		t.Fatalf("期望读取完整的响应体, 得到 %d %d %v", status, len(body), err)
+	}
+
+	status, body, err = Get(ctx, server.URL+"?type=video/mp4", onlyJSON)
+	if err != nil || status != http.StatusOK || body != nil {
+		t.Fatalf("期望不读取响应体, 得到 %d %d %v", status, len(body), err)
+	}
+
+	errTooLarge := errors.New("too large")
+	_, _, err = Get(ctx, server.URL+"?type=video/mp4", WithOnResponseHeaders(func(int, http.Header) (bool, error) { return false, errTooLarge }))
+	if !errors.Is(err, errTooLarge) {
+		t.Fatalf("期望回调返回的错误, 得到 %v", err)
+	}
+
+	var statusErr *HTTPStatusError
+	if _, _, err = Get(ctx, server.URL+"/error", onlyJSON); !errors.As(err, &statusErr) || statusErr.Body != nil {
+		t.Fatalf("放弃读取非 2xx 响应时期望 *HTTPStatusError, 得到 %v", err)
+	}
+}
+
+// TestWithMaxResponseHeaderCount 测试响应头字段数限制
+func TestWithMaxResponseHeaderCount(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 50; i++ {
+			w.Header().Add("X-Tag", strconv.Itoa(i))
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	// 50 个 X-Tag 加上 Content-Length、Content-Type、Date
+	if _, body, err := Get(ctx, server.URL, WithMaxResponseHeaderCount(53)); err != nil || string(body) != "ok" {
+		t.Fatalf("未超过上限时请求应成功, 得到 %q %v", string(body), err)
+	}
+	status, body, err := Get(ctx, server.URL, WithMaxResponseHeaderCount(20))
+	if !errors.Is(err, ErrTooManyHeaders) || status != http.StatusOK || body != nil {
+		t.Fatalf("期望 ErrTooManyHeaders, 得到 %d %q %v", status, string(body), err)
+	}
+	if _, _, err := Get(ctx, server.URL, WithMaxResponseHeaderCount(0)); err == nil {
+		t.Fatal("上限不合法时期望返回错误")
+	}
+}
+
+// TestWithExpectedStatus 测试自定义视为成功的状态码
+func TestWithExpectedStatus(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(r.URL.Query().Get("code"))
+		w.WriteHeader(code)
+		w.Write([]byte("body"))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		code    int
+		options []Option
+		wantErr bool
+	}{
+		{"默认 2xx", http.StatusCreated, nil, false},
+		{"只接受 200", http.StatusCreated, []Option{WithExpectedStatus(http.StatusOK)}, true},
+		{"接受 3xx", http.StatusNotModified, []Option{WithExpectedStatus(http.StatusOK, http.StatusNotModified)}, false},
+		{"接受 404", http.StatusNotFound, []Option{WithExpectedStatus(http.StatusNotFound)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _, err := Get(ctx, server.URL+"?code="+strconv.Itoa(tt.code), tt.options...)
+			if (err != nil) != tt.wantErr || status != tt.code {
+				t.Fatalf("状态码 %d 期望错误 %v, 得到 %d %v", tt.code, tt.wantErr, status, err)
+			}
+			if err != nil && !strings.Contains(err.Error(), "non 200 response") {
+				t.Fatalf("错误消息不符合预期: %v", err)
+			}
+		})
+	}
+}
+
+// TestWithErrorHeader 测试通过响应头表示的错误
+func TestWithErrorHeader(t *testing.T) {
+	ResetDefaultClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Error-Code", r.URL.Query().Get("code"))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("legacy"))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	_, body, err := Get(ctx, server.URL+"?code=E1001", WithErrorHeader("X-Error-Code"))
+	var headerErr *ResponseHeaderError
+	if !errors.As(err, &headerErr) || headerErr.Value != "E1001" {
+		t.Fatalf("期望 ResponseHeaderError, 得到 %v", err)
+	}
+	if string(body) != "legacy" {
+		t.Fatalf("仍应返回响应体, 得到 %q", string(body))
+	}
+
+	// 响应头为空时不算失败
+	if _, _, err := Get(ctx, server.URL, WithErrorHeader("X-Error-Code")); err != nil {
+		t.Fatalf("响应头为空时不应返回错误, 得到 %v", err)
+	}
+}